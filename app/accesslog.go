@@ -0,0 +1,397 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+// Kinds of responses recorded in the access log.
+const (
+	kindMock      = "mock"
+	kindInjected  = "injected"
+	kindProxy     = "proxy"
+	kindStatic    = "static"
+	kindBuiltin   = "builtin"   // /healthcheck and /version
+	kindPreflight = "preflight" // CORS preflight requests
+)
+
+const (
+	formatCombined = "combined"
+	formatJSON     = "json"
+	formatLogfmt   = "logfmt"
+
+	combinedTimeLayout = "02/Jan/2006:15:04:05 -0700"
+	bytesInMegabyte    = 1 << 20
+)
+
+var errUnknownLogFormat = errors.New("unknown access log format")
+
+// AccessLog writes a record for every request handled by the mock server.
+type AccessLog struct {
+	format  string
+	out     io.WriteCloser
+	capture config.CaptureBody
+	lock    sync.Mutex
+}
+
+// accessInfo is filled in by handlers to tell the access log how a request was served.
+type accessInfo struct {
+	endpoint string
+	kind     string
+}
+
+type accessInfoKey struct{}
+
+type accessRecord struct {
+	Time         time.Time `json:"time"`
+	Remote       string    `json:"remote"`
+	User         string    `json:"user,omitempty"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Proto        string    `json:"proto"`
+	Status       int       `json:"status"`
+	Bytes        int64     `json:"bytes"`
+	Duration     float64   `json:"durationMs"`
+	Endpoint     string    `json:"endpoint,omitempty"`
+	Kind         string    `json:"kind,omitempty"`
+	Referer      string    `json:"referer,omitempty"`
+	UserAgent    string    `json:"userAgent,omitempty"`
+	RequestBody  string    `json:"requestBody,omitempty"`
+	ResponseBody string    `json:"responseBody,omitempty"`
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// NewAccessLog creates new AccessLog, returns nil if access logging is not configured.
+func NewAccessLog(cfg *config.AccessLog) (*AccessLog, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	format := cfg.Format
+	if format == "" {
+		format = formatCombined
+	}
+
+	switch format {
+	case formatCombined, formatJSON, formatLogfmt:
+	default:
+		return nil, fmt.Errorf("%w [%s]", errUnknownLogFormat, format)
+	}
+
+	out, err := openAccessLogOutput(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	accessLog := &AccessLog{
+		format: format,
+		out:    out,
+	}
+
+	if cfg.CaptureBody != nil {
+		accessLog.capture = *cfg.CaptureBody
+	}
+
+	return accessLog, nil
+}
+
+func openAccessLogOutput(cfg *config.AccessLog) (io.WriteCloser, error) {
+	switch cfg.Output {
+	case "", "stdout":
+		return nopCloser{os.Stdout}, nil
+	case "stderr":
+		return nopCloser{os.Stderr}, nil
+	}
+
+	if cfg.MaxSize > 0 {
+		return newRotatingFile(cfg.Output, int64(cfg.MaxSize)*bytesInMegabyte, cfg.MaxBackups)
+	}
+
+	file, _, err := openLogFile(cfg.Output)
+
+	return file, err
+}
+
+// Close closes underlying access log output.
+func (a *AccessLog) Close() error {
+	if a == nil {
+		return nil
+	}
+
+	err := a.out.Close()
+	if err != nil {
+		return fmt.Errorf("closing access log: %w", err)
+	}
+
+	return nil
+}
+
+// Middleware returns the access log middleware handler.
+func (a *AccessLog) Middleware(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		info := &accessInfo{}
+
+		var reqBody *limitedBuffer
+
+		if a.capture.Request > 0 && req.Body != nil {
+			reqBody = &limitedBuffer{limit: a.capture.Request}
+			req.Body = &captureReader{ReadCloser: req.Body, buf: reqBody}
+		}
+
+		recorder := &accessWriter{ResponseWriter: writer}
+		if a.capture.Response > 0 {
+			recorder.body = &limitedBuffer{limit: a.capture.Response}
+		}
+
+		next.ServeHTTP(recorder, req.WithContext(context.WithValue(req.Context(), accessInfoKey{}, info)))
+
+		record := newAccessRecord(req, recorder, info, start)
+		if reqBody != nil {
+			record.RequestBody = reqBody.String()
+		}
+
+		if recorder.body != nil {
+			record.ResponseBody = recorder.body.String()
+		}
+
+		a.write(record)
+	})
+}
+
+func newAccessRecord(req *http.Request, recorder *accessWriter, info *accessInfo, start time.Time) *accessRecord {
+	remote, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		remote = req.RemoteAddr
+	}
+
+	user, _, _ := req.BasicAuth()
+
+	status := recorder.status
+	if status == 0 && !recorder.hijacked {
+		status = http.StatusOK
+	}
+
+	return &accessRecord{
+		Time:      start,
+		Remote:    remote,
+		User:      user,
+		Method:    req.Method,
+		Path:      req.RequestURI,
+		Proto:     req.Proto,
+		Status:    status,
+		Bytes:     recorder.written,
+		Duration:  float64(time.Since(start)) / float64(time.Millisecond),
+		Endpoint:  info.endpoint,
+		Kind:      info.kind,
+		Referer:   req.Referer(),
+		UserAgent: req.UserAgent(),
+	}
+}
+
+func (a *AccessLog) write(record *accessRecord) {
+	var buf bytes.Buffer
+
+	switch a.format {
+	case formatJSON:
+		_ = json.NewEncoder(&buf).Encode(record)
+	case formatLogfmt:
+		writeLogfmt(&buf, record)
+	default:
+		writeCombined(&buf, record)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	_, _ = a.out.Write(buf.Bytes())
+}
+
+func writeCombined(buf *bytes.Buffer, record *accessRecord) {
+	_, _ = fmt.Fprintf(buf, "%s - %s [%s] \"%s %s %s\" %d %d %q %q rt=%.3fms endpoint=%q kind=%s",
+		record.Remote, dashIfEmpty(record.User), record.Time.Format(combinedTimeLayout),
+		record.Method, record.Path, record.Proto, record.Status, record.Bytes,
+		dashIfEmpty(record.Referer), dashIfEmpty(record.UserAgent),
+		record.Duration, record.Endpoint, dashIfEmpty(record.Kind))
+
+	if record.RequestBody != "" {
+		_, _ = fmt.Fprintf(buf, " requestBody=%q", record.RequestBody)
+	}
+
+	if record.ResponseBody != "" {
+		_, _ = fmt.Fprintf(buf, " responseBody=%q", record.ResponseBody)
+	}
+
+	buf.WriteByte('\n')
+}
+
+func writeLogfmt(buf *bytes.Buffer, record *accessRecord) {
+	pairs := [][2]string{
+		{"time", record.Time.Format(time.RFC3339Nano)},
+		{"remote", record.Remote},
+		{"method", record.Method},
+		{"path", record.Path},
+		{"proto", record.Proto},
+		{"status", strconv.Itoa(record.Status)},
+		{"bytes", strconv.FormatInt(record.Bytes, 10)},
+		{"durationMs", strconv.FormatFloat(record.Duration, 'f', 3, 64)},
+	}
+
+	// Optional fields are only written when present.
+	for _, pair := range [][2]string{
+		{"user", record.User},
+		{"endpoint", record.Endpoint},
+		{"kind", record.Kind},
+		{"referer", record.Referer},
+		{"userAgent", record.UserAgent},
+		{"requestBody", record.RequestBody},
+		{"responseBody", record.ResponseBody},
+	} {
+		if pair[1] != "" {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	for idx, pair := range pairs {
+		if idx > 0 {
+			buf.WriteByte(' ')
+		}
+
+		buf.WriteString(pair[0])
+		buf.WriteByte('=')
+		buf.WriteString(logfmtValue(pair[1]))
+	}
+
+	buf.WriteByte('\n')
+}
+
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\\\t\r\n") {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+func dashIfEmpty(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+// setAccessInfo records how the request was served, it is a no-op when access log is disabled.
+func setAccessInfo(req *http.Request, endpoint, kind string) {
+	info, ok := req.Context().Value(accessInfoKey{}).(*accessInfo)
+	if !ok {
+		return
+	}
+
+	info.endpoint = endpoint
+	info.kind = kind
+}
+
+// accessWriter records status, size and optionally the body of a response.
+type accessWriter struct {
+	http.ResponseWriter
+
+	status   int
+	written  int64
+	body     *limitedBuffer
+	hijacked bool
+}
+
+func (w *accessWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *accessWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(data)
+	w.written += int64(n)
+
+	if w.body != nil {
+		_, _ = w.body.Write(data[:n])
+	}
+
+	return n, err //nolint:wrapcheck // transparent wrapper
+}
+
+func (w *accessWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, bufrw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, fmt.Errorf("hijacking connection: %w", err)
+	}
+
+	w.hijacked = true
+
+	return conn, bufrw, nil
+}
+
+func (w *accessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// captureReader copies read request body into the buffer.
+type captureReader struct {
+	io.ReadCloser
+
+	buf *limitedBuffer
+}
+
+func (r *captureReader) Read(data []byte) (int, error) {
+	n, err := r.ReadCloser.Read(data)
+	_, _ = r.buf.Write(data[:n])
+
+	return n, err //nolint:wrapcheck // transparent wrapper
+}
+
+// limitedBuffer keeps at most limit bytes and drops the rest, truncated tells if anything was dropped.
+type limitedBuffer struct {
+	bytes.Buffer
+
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	left := b.limit - b.Len()
+	if left > 0 {
+		b.Buffer.Write(data[:min(left, len(data))])
+	}
+
+	b.truncated = b.truncated || len(data) > max(left, 0)
+
+	return len(data), nil
+}
@@ -0,0 +1,101 @@
+package app //nolint:testpackage // testing unexported access log internals
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+func Test_AccessLog_JSON(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "access.log")
+
+	accessLog, err := NewAccessLog(&config.AccessLog{
+		Format:      formatJSON,
+		Output:      file,
+		CaptureBody: &config.CaptureBody{Request: 3, Response: 100},
+	})
+	require.NoError(t, err)
+
+	handler := accessLog.Middleware(RegisterHandlers("test", ".", &config.Config{}, &config.Mock{
+		Endpoints: []*config.Endpoint{
+			{Methods: []string{http.MethodPost}, Path: "/users", JSON: map[string]any{"id": 1}},
+		},
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("hello"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, accessLog.Close())
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	var record accessRecord
+	require.NoError(t, json.Unmarshal(data, &record))
+	assert.Equal(t, http.MethodPost, record.Method)
+	assert.Equal(t, "/users", record.Path)
+	assert.Equal(t, "/users", record.Endpoint)
+	assert.Equal(t, kindMock, record.Kind)
+	assert.Equal(t, http.StatusOK, record.Status)
+	assert.Equal(t, "{\"id\":1}\n", record.ResponseBody)
+	assert.Equal(t, int64(len(record.ResponseBody)), record.Bytes)
+}
+
+func Test_AccessLog_Logfmt(t *testing.T) {
+	t.Parallel()
+
+	var buf limitedBuffer
+
+	buf.limit = 1024
+	accessLog := &AccessLog{format: formatLogfmt, out: nopCloser{&buf}}
+
+	handler := accessLog.Middleware(http.NotFoundHandler())
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing?q=a%20b", nil))
+
+	line := buf.String()
+	assert.Contains(t, line, "method=GET")
+	assert.Contains(t, line, `path="/missing?q=a%20b"`)
+	assert.Contains(t, line, "status=404")
+	assert.NotContains(t, line, "kind=")
+}
+
+func Test_RotatingFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	file, err := newRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err = file.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, file.Close())
+
+	assertFileContent(t, path, "fourth\n")
+	assertFileContent(t, path+".1", "third\n")
+	assertFileContent(t, path+".2", "second\n")
+
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func assertFileContent(t *testing.T, path, expected string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(data))
+}
@@ -22,9 +22,19 @@ func RegisterHandlers(version, mockPath string, cfg *config.Config, mck *config.
 	// Shows current version of the App
 	router.Method(http.MethodGet, "/version", appHandler(versionHandler(version)))
 
-	setupAPI(cfg, mockPath, mck, router)
+	iss := setupIssuer(mockPath, mck, router)
 
-	return router
+	setupAPI(cfg, mockPath, mck, iss, router)
+	setupFallback(cfg, mockPath, mck, router)
+
+	rpl, err := newReplay(mockPath, mck.Replay, slog.Default().With("endpoint", kindReplay))
+	if err != nil {
+		slog.Error(fmt.Sprintf("error in setting up replay: %v", err))
+
+		return unusableReplay(err)
+	}
+
+	return rpl.Middleware(router)
 }
 
 // http://blog.golang.org/error-handling-and-go
@@ -52,9 +62,12 @@ func (fn appHandler) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
 	}
 }
 
-// writeResponse writes response to provided ResponseWriter in JSON format.
+// writeResponse writes response to provided ResponseWriter in JSON format,
+// keeps Content-Type if it is already set.
 func writeResponse(writer http.ResponseWriter, response any) *appError {
-	writer.Header().Set("Content-Type", "application/json")
+	if writer.Header().Get("Content-Type") == "" {
+		writer.Header().Set("Content-Type", "application/json")
+	}
 
 	err := json.NewEncoder(writer).Encode(response)
 	if err != nil {
@@ -0,0 +1,243 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	kindAuth     = "auth"
+	defaultRealm = "gomock"
+
+	schemeBasic  = "Basic"
+	schemeBearer = "Bearer"
+	schemeAPIKey = "ApiKey"
+)
+
+var (
+	errNoCredentials      = errors.New("authentication required")
+	errInvalidCredentials = errors.New("invalid credentials")
+	errInvalidToken       = errors.New("invalid token")
+	errNotAllowed         = errors.New("principal is not allowed")
+)
+
+type identityKey struct{}
+
+// identity is the authenticated principal of a request, claims are set for JWTs.
+type identity struct {
+	principal string
+	claims    map[string]any
+}
+
+// principalOf returns the authenticated principal of the request, empty if the endpoint requires no credentials.
+func principalOf(req *http.Request) string {
+	if ident, ok := req.Context().Value(identityKey{}).(*identity); ok {
+		return ident.principal
+	}
+
+	return ""
+}
+
+// claimsOf returns claims of the verified bearer JWT of the request, nil if there is none.
+func claimsOf(req *http.Request) map[string]any {
+	if ident, ok := req.Context().Value(identityKey{}).(*identity); ok {
+		return ident.claims
+	}
+
+	return nil
+}
+
+// authenticator checks credentials of requests to an endpoint.
+type authenticator struct {
+	path         string // path of the endpoint
+	cfg          *config.Auth
+	realm        string
+	perPrincipal bool    // dynamic entities are kept per principal, so every request needs one
+	issuer       *issuer // verifies JWTs
+	log          *slog.Logger
+}
+
+// newAuthenticator creates new authenticator, returns nil if the endpoint requires no credentials.
+func newAuthenticator(endpoint *config.Endpoint, global *config.Auth, iss *issuer,
+	log *slog.Logger) (*authenticator, error) {
+	cfg := endpoint.Auth
+	if cfg == nil {
+		cfg = global
+	}
+
+	if cfg == nil || cfg.Disabled {
+		return nil, nil
+	}
+
+	if cfg.JWT != nil && iss == nil {
+		return nil, errNoIssuer
+	}
+
+	realm := cfg.Realm
+	if realm == "" {
+		realm = defaultRealm
+	}
+
+	return &authenticator{
+		path:         endpoint.Path,
+		cfg:          cfg,
+		realm:        realm,
+		perPrincipal: endpoint.Dynamic != nil && endpoint.Dynamic.PerPrincipal,
+		issuer:       iss,
+		log:          log,
+	}, nil
+}
+
+// Middleware returns the middleware handler, which responds with 401 to requests without valid credentials
+// and with 403 to principals, which aren't allowed or lack required scopes.
+func (a *authenticator) Middleware(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		ident, err := a.authenticate(req)
+		if err == nil && len(a.cfg.Allow) > 0 && !slices.Contains(a.cfg.Allow, ident.principal) {
+			err = fmt.Errorf("%w [%s]", errNotAllowed, ident.principal)
+		}
+
+		if err != nil {
+			a.log.Debug("rejecting request", "uri", req.RequestURI, "error", err)
+			setAccessInfo(req, a.path, kindAuth)
+			a.reject(writer, err)
+
+			return
+		}
+
+		next.ServeHTTP(writer, req.WithContext(context.WithValue(req.Context(), identityKey{}, ident)))
+	})
+}
+
+// authenticate returns the identity of valid credentials of the request, the Authorization header
+// takes precedence over API keys.
+func (a *authenticator) authenticate(req *http.Request) (*identity, error) {
+	scheme, _, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+
+	switch {
+	case strings.EqualFold(scheme, schemeBasic) && len(a.cfg.Basic) > 0:
+		username, password, ok := req.BasicAuth()
+		if expected, found := a.cfg.Basic[username]; !ok || !found || expected != password {
+			return nil, errInvalidCredentials
+		}
+
+		return &identity{principal: username}, nil
+	case strings.EqualFold(scheme, schemeBearer) && a.bearer():
+		return a.authenticateToken(bearerToken(req))
+	}
+
+	if key := a.apiKey(req); key != "" {
+		principal, found := a.cfg.APIKey.Keys[key]
+		if !found {
+			return nil, errInvalidCredentials
+		}
+
+		return &identity{principal: cmp.Or(principal, key)}, nil
+	}
+
+	return nil, errNoCredentials
+}
+
+// bearer tells if bearer tokens are accepted.
+func (a *authenticator) bearer() bool {
+	return len(a.cfg.Bearer) > 0 || a.cfg.JWT != nil
+}
+
+// authenticateToken checks static tokens first and then verifies the token as a JWT.
+func (a *authenticator) authenticateToken(token string) (*identity, error) {
+	if principal, found := a.cfg.Bearer[token]; found {
+		return &identity{principal: cmp.Or(principal, token)}, nil
+	}
+
+	if a.cfg.JWT == nil {
+		return nil, errInvalidToken
+	}
+
+	claims, err := a.issuer.verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkClaims(a.cfg.JWT, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	claim := cmp.Or(a.cfg.JWT.Principal, "sub")
+	principal, _ := claims[claim].(string)
+
+	// Without a principal the token would get entities shared by everyone.
+	if principal == "" && a.perPrincipal {
+		return nil, fmt.Errorf("%w: no %s claim", errInvalidToken, claim)
+	}
+
+	return &identity{principal: principal, claims: claims}, nil
+}
+
+func (a *authenticator) apiKey(req *http.Request) string {
+	if a.cfg.APIKey == nil {
+		return ""
+	}
+
+	if a.cfg.APIKey.Header != "" {
+		if key := req.Header.Get(a.cfg.APIKey.Header); key != "" {
+			return key
+		}
+	}
+
+	if a.cfg.APIKey.Query != "" {
+		return req.URL.Query().Get(a.cfg.APIKey.Query)
+	}
+
+	return ""
+}
+
+// reject responds with 403 to principals, which aren't allowed or lack required scopes, and with 401
+// with challenges of the configured schemes otherwise.
+func (a *authenticator) reject(writer http.ResponseWriter, err error) {
+	realm := fmt.Sprintf("realm=%q", a.realm)
+
+	switch {
+	case errors.Is(err, errNotAllowed):
+		http.Error(writer, err.Error(), http.StatusForbidden)
+
+		return
+	case errors.Is(err, errInsufficientScope):
+		writer.Header().Set("WWW-Authenticate", fmt.Sprintf(`%s %s, error="insufficient_scope", scope=%q`,
+			schemeBearer, realm, strings.Join(a.cfg.JWT.Scopes, " ")))
+		http.Error(writer, err.Error(), http.StatusForbidden)
+
+		return
+	}
+
+	if len(a.cfg.Basic) > 0 {
+		writer.Header().Add("WWW-Authenticate", schemeBasic+" "+realm+`, charset="UTF-8"`)
+	}
+
+	if a.bearer() {
+		challenge := schemeBearer + " " + realm
+		if errors.Is(err, errInvalidToken) {
+			challenge += `, error="invalid_token"`
+		}
+
+		writer.Header().Add("WWW-Authenticate", challenge)
+	}
+
+	if a.cfg.APIKey != nil {
+		writer.Header().Add("WWW-Authenticate", schemeAPIKey+" "+realm)
+	}
+
+	http.Error(writer, err.Error(), http.StatusUnauthorized)
+}
@@ -0,0 +1,174 @@
+package app_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func authRequest(handler http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestAuth_Schemes(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"endpoints": [
+		{"path": "/me", "body": "hello {{.Principal}}", "auth": {
+			"realm": "shop",
+			"basic": {"alice": "secret"},
+			"bearer": {"t0k3n": "bob", "raw-token": ""},
+			"apiKey": {"header": "X-API-Key", "query": "api_key", "keys": {"k1": "carol"}}
+		}}
+	]}`)
+
+	for _, tc := range []struct {
+		name    string
+		path    string
+		headers map[string]string
+		status  int
+		body    string
+	}{
+		{"basic", "/me", map[string]string{"Authorization": "Basic YWxpY2U6c2VjcmV0"}, http.StatusOK, "hello alice"},
+		{"bearer", "/me", map[string]string{"Authorization": "Bearer t0k3n"}, http.StatusOK, "hello bob"},
+		{"bearer token principal", "/me", map[string]string{"Authorization": "bearer raw-token"}, http.StatusOK,
+			"hello raw-token"},
+		{"API key header", "/me", map[string]string{"X-API-Key": "k1"}, http.StatusOK, "hello carol"},
+		{"API key query", "/me?api_key=k1", nil, http.StatusOK, "hello carol"},
+		{"no credentials", "/me", nil, http.StatusUnauthorized, "authentication required"},
+		{"wrong password", "/me", map[string]string{"Authorization": "Basic YWxpY2U6d3Jvbmc="}, http.StatusUnauthorized,
+			"invalid credentials"},
+		{"unknown key", "/me", map[string]string{"X-API-Key": "k2"}, http.StatusUnauthorized, "invalid credentials"},
+		{"unknown token", "/me", map[string]string{"Authorization": "Bearer nope"}, http.StatusUnauthorized,
+			"invalid token"},
+	} {
+		rec := authRequest(handler, http.MethodGet, tc.path, "", tc.headers)
+
+		assert.Equal(t, tc.status, rec.Code, tc.name)
+		assert.Contains(t, rec.Body.String(), tc.body, tc.name)
+
+		if tc.status == http.StatusUnauthorized {
+			challenges := rec.Header().Values("WWW-Authenticate")
+			assert.Contains(t, challenges, `Basic realm="shop", charset="UTF-8"`, tc.name)
+			assert.Contains(t, challenges, `ApiKey realm="shop"`, tc.name)
+
+			if tc.name == "unknown token" {
+				assert.Contains(t, challenges, `Bearer realm="shop", error="invalid_token"`, tc.name)
+			} else {
+				assert.Contains(t, challenges, `Bearer realm="shop"`, tc.name)
+			}
+		}
+	}
+}
+
+func TestAuth_GlobalAllowAndDisabled(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"auth": {"bearer": {"admin-token": "admin", "user-token": "user"}},
+		"endpoints": [
+			{"path": "/users", "json": []},
+			{"path": "/admin", "json": {"ok": true}, "auth": {"bearer": {"admin-token": "admin", "user-token": "user"},
+				"allow": ["admin"]}},
+			{"path": "/public", "json": {"ok": true}, "auth": {"disabled": true}},
+			{"path": "/cors", "json": {}, "allowCors": ["*"]}
+		]}`)
+
+	user := map[string]string{"Authorization": "Bearer user-token"}
+	admin := map[string]string{"Authorization": "Bearer admin-token"}
+
+	assert.Equal(t, http.StatusUnauthorized, authRequest(handler, http.MethodGet, "/users", "", nil).Code)
+	assert.Equal(t, http.StatusOK, authRequest(handler, http.MethodGet, "/users", "", user).Code)
+	assert.Equal(t, http.StatusForbidden, authRequest(handler, http.MethodGet, "/admin", "", user).Code)
+	assert.Empty(t, authRequest(handler, http.MethodGet, "/admin", "", user).Header().Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusOK, authRequest(handler, http.MethodGet, "/admin", "", admin).Code)
+	assert.Equal(t, http.StatusOK, authRequest(handler, http.MethodGet, "/public", "", nil).Code)
+
+	// Preflight requests carry no credentials.
+	rec := authRequest(handler, http.MethodOptions, "/cors", "", map[string]string{
+		"Origin":                        "https://app.example.com",
+		"Access-Control-Request-Method": http.MethodGet,
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	rec = authRequest(handler, http.MethodGet, "/cors", "", map[string]string{"Origin": "https://app.example.com"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestAuth_DynamicPerPrincipal(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"auth": {"basic": {"alice": "a", "bob": "b"}}, "endpoints": [
+		{"methods": ["PUT"], "path": "/notes", "dynamic": {"perPrincipal": true,
+			"write": {"json": {"name": "note", "key": "id", "value": "."}}}},
+		{"path": "/notes/{id}", "dynamic": {"perPrincipal": true, "read": {"json": {"name": "note", "keyParam": "id"}}}},
+		{"path": "/notes", "dynamic": {"perPrincipal": true, "read": {"json": {"name": "note"}}}}
+	]}`)
+
+	alice := map[string]string{"Authorization": "Basic YWxpY2U6YQ==", "Content-Type": "application/json"}
+	bob := map[string]string{"Authorization": "Basic Ym9iOmI=", "Content-Type": "application/json"}
+
+	rec := authRequest(handler, http.MethodPut, "/notes", `{"id": "1", "text": "alice's"}`, alice)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = authRequest(handler, http.MethodPut, "/notes", `{"id": "2", "text": "bob's"}`, bob)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = authRequest(handler, http.MethodGet, "/notes/1", "", alice)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id": "1", "text": "alice's"}`, rec.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, authRequest(handler, http.MethodGet, "/notes/1", "", bob).Code)
+
+	rec = authRequest(handler, http.MethodGet, "/notes", "", bob)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"2": {"id": "2", "text": "bob's"}}`, rec.Body.String())
+}
+
+func TestAuth_PerPrincipalUploadsStayInDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	mock := `{"auth": {"bearer": {"dots": "..", "alice": ""}}, "endpoints": [
+		{"methods": ["POST"], "path": "/files", "dynamic": {"perPrincipal": true,
+			"write": {"files": {"name": "file", "dir": "uploads"}}}}
+	]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mock.json"), []byte(mock), 0o600))
+
+	handler := newMockHandler(t, dir, mock)
+
+	for _, token := range []string{"dots", "alice"} {
+		body, contentType := multipartBody(t, nil, "file", "mock.json", "overwritten")
+		req := httptest.NewRequest(http.MethodPost, "/files", body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, token)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "mock.json"))
+	require.NoError(t, err)
+	assert.Equal(t, mock, string(data))
+
+	// Every principal gets its own directory inside the uploads directory.
+	entries, err := os.ReadDir(filepath.Join(dir, "uploads"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	contentTypeJSON  = "application/json"
+	contentTypeText  = "text/plain; charset=utf-8"
+	contentTypeBytes = "application/octet-stream"
+)
+
+// extensionTypes complements mime package, which depends on the system tables for these.
+var extensionTypes = map[string]string{
+	".csv":   "text/csv; charset=utf-8",
+	".txt":   contentTypeText,
+	".yaml":  "application/yaml",
+	".yml":   "application/yaml",
+	".pb":    "application/x-protobuf",
+	".proto": "application/x-protobuf",
+	".bin":   contentTypeBytes,
+	".wsdl":  "application/xml",
+	".xsd":   "application/xml",
+}
+
+// responseBody is a response body of an endpoint resolved at setup.
+type responseBody struct {
+	data        []byte
+	contentType string
+	tmpl        *valueTemplate // set if the body is a template
+	modTime     time.Time      // modification time of the file with the body
+	generated   *generator     // set if the body is synthetic
+}
+
+// seekable tells if the body is served with support of byte ranges.
+func (b *responseBody) seekable() bool {
+	return b.generated != nil || !b.modTime.IsZero()
+}
+
+func (b *responseBody) templated() bool {
+	return b != nil && b.tmpl != nil
+}
+
+func (b *responseBody) render(data *templateData) []byte {
+	if b.tmpl == nil {
+		return b.data
+	}
+
+	return []byte(b.tmpl.render(data))
+}
+
+// loadBody resolves static body of the endpoint, returns nil if the endpoint has none.
+func loadBody(mockPath string, endpoint *config.Endpoint) (*responseBody, error) {
+	switch {
+	case endpoint.SOAPFault != nil:
+		return soapFaultBody(endpoint.SOAPFault), nil
+	case endpoint.JSONPath != "":
+		data, err := readFile(mockPath, endpoint.JSONPath)
+		if err != nil {
+			return nil, err
+		}
+
+		return &responseBody{
+			data:        data,
+			contentType: contentTypeJSON,
+			modTime:     fileModTime(mockPath, endpoint.JSONPath),
+		}, nil
+	case endpoint.JSON != nil:
+		data, err := json.Marshal(endpoint.JSON)
+		if err != nil {
+			return nil, fmt.Errorf("encoding JSON: %w", err)
+		}
+
+		return &responseBody{data: append(data, '\n'), contentType: contentTypeJSON}, nil
+	case endpoint.Body != "":
+		tmpl, err := newValueTemplate("body", endpoint.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		body := &responseBody{data: []byte(endpoint.Body), contentType: contentTypeText}
+		if tmpl.tmpl != nil {
+			body.tmpl = tmpl
+		}
+
+		return body, nil
+	case endpoint.BodyBase64 != "":
+		data, err := base64.StdEncoding.DecodeString(endpoint.BodyBase64)
+		if err != nil {
+			return nil, fmt.Errorf("decoding base64 body: %w", err)
+		}
+
+		return &responseBody{data: data, contentType: http.DetectContentType(data)}, nil
+	case endpoint.BodyFile != "":
+		data, err := readFile(mockPath, endpoint.BodyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return &responseBody{
+			data:        data,
+			contentType: contentTypeOf(endpoint.BodyFile, data),
+			modTime:     fileModTime(mockPath, endpoint.BodyFile),
+		}, nil
+	case endpoint.Generate != nil:
+		gen, err := newGenerator(endpoint.Generate)
+		if err != nil {
+			return nil, err
+		}
+
+		contentType := typeByExtension(gen.name)
+		if contentType == "" {
+			contentType = contentTypeBytes
+		}
+
+		return &responseBody{contentType: contentType, generated: gen}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// contentTypeOf infers content type from the file extension, falling back to sniffing the content.
+func contentTypeOf(name string, data []byte) string {
+	if contentType := typeByExtension(name); contentType != "" {
+		return contentType
+	}
+
+	return http.DetectContentType(data)
+}
+
+// typeByExtension returns content type of the file extension, empty if it is unknown.
+func typeByExtension(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+
+	if contentType, ok := extensionTypes[ext]; ok {
+		return contentType
+	}
+
+	return mime.TypeByExtension(ext)
+}
+
+func readFile(mockPath, filePath string) ([]byte, error) {
+	fullPath := filepath.Join(mockPath, filePath)
+
+	data, err := os.ReadFile(filepath.Clean(fullPath))
+	if err != nil {
+		return nil, fmt.Errorf("reading file %s: %w", fullPath, err)
+	}
+
+	return data, nil
+}
+
+func fileModTime(mockPath, filePath string) time.Time {
+	info, err := os.Stat(filepath.Join(mockPath, filePath))
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
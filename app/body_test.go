@@ -0,0 +1,57 @@
+package app_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock/app"
+	"github.com/smeshkov/gomock/config"
+)
+
+func TestBody_NonJSON(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.csv"), []byte("id,name\n1,foo\n"), 0o600))
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	handler := app.RegisterHandlers("test", dir, &config.Config{}, &config.Mock{
+		Endpoints: []*config.Endpoint{
+			{
+				Methods: []string{http.MethodGet},
+				Path:    "/partner",
+				Body:    "<user><id>1</id></user>",
+				Headers: map[string]string{"Content-Type": "application/xml"},
+			},
+			{Methods: []string{http.MethodGet}, Path: "/text", Body: "hello"},
+			{Methods: []string{http.MethodGet}, Path: "/image", BodyBase64: base64.StdEncoding.EncodeToString(png)},
+			{Methods: []string{http.MethodGet}, Path: "/report", BodyFile: "report.csv"},
+		},
+	})
+
+	for _, tc := range []struct {
+		path        string
+		contentType string
+		body        string
+	}{
+		{"/partner", "application/xml", "<user><id>1</id></user>"},
+		{"/text", "text/plain; charset=utf-8", "hello"},
+		{"/image", "image/png", string(png)},
+		{"/report", "text/csv; charset=utf-8", "id,name\n1,foo\n"},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code, tc.path)
+		assert.Equal(t, tc.contentType, rec.Header().Get("Content-Type"), tc.path)
+		assert.Equal(t, tc.body, rec.Body.String(), tc.path)
+	}
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+// etagSize is the number of bytes of the body digest used in computed entity tags.
+const etagSize = 16
+
+// cachePolicy holds validators and caching headers of an endpoint.
+type cachePolicy struct {
+	etag         string // fixed entity tag, computed from the body if empty
+	lastModified time.Time
+	control      string
+	vary         string
+}
+
+// newCachePolicy resolves caching of the endpoint, returns nil if the endpoint has none.
+func newCachePolicy(cfg *config.Cache, body *responseBody) (*cachePolicy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	policy := &cachePolicy{
+		etag:    cfg.ETag,
+		control: cfg.Control,
+		vary:    strings.Join(cfg.Vary, ", "),
+	}
+
+	if policy.etag != "" && !strings.HasSuffix(policy.etag, `"`) {
+		policy.etag = `"` + policy.etag + `"`
+	}
+
+	if cfg.LastModified != "" {
+		lastModified, err := http.ParseTime(cfg.LastModified)
+		if err != nil {
+			return nil, fmt.Errorf("parsing lastModified [%s]: %w", cfg.LastModified, err)
+		}
+
+		policy.lastModified = lastModified
+	} else if body != nil {
+		policy.lastModified = body.modTime
+	}
+
+	return policy, nil
+}
+
+// validate sets caching headers and validators of a response with the given entity tag,
+// returns true if the request has to be answered with 304 Not Modified instead.
+// Every response with a body gets its entity tag, so that clients can revalidate any of them,
+// the policy adds a fixed tag, Last-Modified and caching headers. Without a policy only the entity tag is set.
+func (c *cachePolicy) validate(writer http.ResponseWriter, req *http.Request, status int, etag string) bool {
+	var lastModified time.Time
+
+	// Entity tags set by headers of the endpoint are kept.
+	if fixed := writer.Header().Get("ETag"); fixed != "" {
+		etag = fixed
+	}
+
+	if c != nil {
+		if c.etag != "" {
+			etag = c.etag
+		}
+
+		lastModified = c.lastModified
+
+		if c.control != "" {
+			writer.Header().Set("Cache-Control", c.control)
+		}
+
+		for field := range strings.SplitSeq(c.vary, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				addVary(writer.Header(), field)
+			}
+		}
+	}
+
+	if etag != "" {
+		writer.Header().Set("ETag", etag)
+	}
+
+	if !lastModified.IsZero() {
+		writer.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if status != http.StatusOK || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return false
+	}
+
+	// If-None-Match takes precedence over If-Modified-Since.
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && etagMatches(inm, etag, true)
+	}
+
+	if ims := req.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// entityTag computes a strong entity tag of the content.
+func entityTag(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return `"` + hex.EncodeToString(sum[:etagSize]) + `"`
+}
+
+// etagMatches tells if the entity tag is in the list of a conditional header, "*" matches any tag.
+// Weak comparison ignores "W/" prefixes, strong comparison never matches weak tags.
+func etagMatches(header, etag string, weak bool) bool {
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" {
+			return true
+		}
+
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+			etag = strings.TrimPrefix(etag, "W/")
+		} else if strings.HasPrefix(candidate, "W/") || strings.HasPrefix(etag, "W/") {
+			continue
+		}
+
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writePreconditionsMet evaluates If-Match and If-None-Match of a write against the current value.
+func writePreconditionsMet(req *http.Request, currentTag string, exists bool) bool {
+	if im := req.Header.Get("If-Match"); im != "" && (!exists || !etagMatches(im, currentTag, false)) {
+		return false
+	}
+
+	if inm := req.Header.Get("If-None-Match"); inm != "" && exists && etagMatches(inm, currentTag, true) {
+		return false
+	}
+
+	return true
+}
+
+// valueTag computes the entity tag of a dynamic value as it is served by dynamic reads.
+func valueTag(value any) string {
+	content, err := encodeJSON(value)
+	if err != nil {
+		return ""
+	}
+
+	return entityTag(content)
+}
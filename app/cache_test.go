@@ -0,0 +1,129 @@
+package app_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_ConditionalGet(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"endpoints": [
+		{"path": "/users", "json": {"id": 1},
+		 "cache": {"lastModified": "Wed, 21 Oct 2026 07:28:00 GMT", "control": "max-age=60", "vary": ["Accept", "Accept-Language"]}},
+		{"path": "/config", "json": {"id": 2}, "cache": {"etag": "v1"}},
+		{"path": "/plain", "json": {"id": 3}}
+	]}`)
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := get("/users", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "max-age=60", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "Accept, Accept-Language", rec.Header().Get("Vary"))
+	assert.Equal(t, "Wed, 21 Oct 2026 07:28:00 GMT", rec.Header().Get("Last-Modified"))
+
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	rec = get("/users", map[string]string{"If-None-Match": `"other", W/` + etag})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = get("/users", map[string]string{"If-None-Match": `"other"`})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = get("/users", map[string]string{"If-Modified-Since": "Wed, 21 Oct 2026 07:28:00 GMT"})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = get("/users", map[string]string{"If-Modified-Since": "Tue, 20 Oct 2026 07:28:00 GMT"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// If-None-Match takes precedence.
+	rec = get("/users", map[string]string{
+		"If-None-Match":     `"other"`,
+		"If-Modified-Since": "Wed, 21 Oct 2026 07:28:00 GMT",
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = get("/config", map[string]string{"If-None-Match": `"v1"`})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, `"v1"`, rec.Header().Get("ETag"))
+
+	// Responses without cache get an entity tag computed from the body as well.
+	rec = get("/plain", nil)
+	assert.Empty(t, rec.Header().Get("Last-Modified"))
+
+	etag = rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, http.StatusNotModified, get("/plain", map[string]string{"If-None-Match": etag}).Code)
+}
+
+func TestCache_DynamicPreconditions(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"endpoints": [
+		{"methods": ["PUT"], "path": "/notes", "dynamic": {"write": {"json": {"name": "note", "key": "id", "value": "."}}}},
+		{"path": "/notes/{id}", "dynamic": {"read": {"json": {"name": "note", "keyParam": "id"}}}}
+	]}`)
+
+	serve := func(method, body string, headers map[string]string) *httptest.ResponseRecorder {
+		target := "/notes"
+		if method == http.MethodGet {
+			target = "/notes/1"
+		}
+
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	// Create only if absent.
+	rec := serve(http.MethodPut, `{"id": "1", "text": "a"}`, map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	created := rec.Header().Get("ETag")
+	require.NotEmpty(t, created)
+
+	rec = serve(http.MethodPut, `{"id": "1", "text": "b"}`, map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = serve(http.MethodGet, "", nil)
+	assert.Equal(t, created, rec.Header().Get("ETag"))
+	assert.JSONEq(t, `{"id": "1", "text": "a"}`, rec.Body.String())
+
+	rec = serve(http.MethodGet, "", map[string]string{"If-None-Match": created})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	// Optimistic concurrency.
+	rec = serve(http.MethodPut, `{"id": "1", "text": "c"}`, map[string]string{"If-Match": created})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, created, rec.Header().Get("ETag"))
+
+	rec = serve(http.MethodPut, `{"id": "1", "text": "d"}`, map[string]string{"If-Match": created})
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = serve(http.MethodGet, "", nil)
+	assert.JSONEq(t, `{"id": "1", "text": "c"}`, rec.Body.String())
+}
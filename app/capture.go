@@ -0,0 +1,102 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// captureVersion is the version of gomock capture format.
+const captureVersion = 1
+
+var errUnknownCapture = errors.New("neither HAR nor gomock capture")
+
+// Capture is gomock's format of recorded traffic.
+type Capture struct {
+	Version int             `json:"version"`
+	Entries []*JournalEntry `json:"entries"`
+}
+
+// Save writes entries of the journal to the file, in HAR 1.2 format if the file has ".har" extension
+// and in gomock capture format otherwise.
+func (j *Journal) Save(file string) error {
+	var capture any = &Capture{Version: captureVersion, Entries: j.Entries()}
+	if strings.EqualFold(filepath.Ext(file), ".har") {
+		capture = newHARFile(j.Entries())
+	}
+
+	data, err := json.MarshalIndent(capture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding capture: %w", err)
+	}
+
+	err = os.WriteFile(filepath.Clean(file), data, 0o600)
+	if err != nil {
+		return fmt.Errorf("writing capture: %w", err)
+	}
+
+	return nil
+}
+
+// LoadCapture reads recorded traffic from the HAR or gomock capture file.
+func LoadCapture(file string) ([]*JournalEntry, error) {
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, fmt.Errorf("reading capture: %w", err)
+	}
+
+	return decodeCapture(data)
+}
+
+func decodeCapture(data []byte) ([]*JournalEntry, error) {
+	var probe struct {
+		Log     *json.RawMessage `json:"log"`
+		Entries *json.RawMessage `json:"entries"`
+	}
+
+	err := json.Unmarshal(data, &probe)
+	if err != nil {
+		return nil, fmt.Errorf("decoding capture: %w", err)
+	}
+
+	switch {
+	case probe.Log != nil:
+		return loadHAR(data)
+	case probe.Entries != nil:
+		var capture Capture
+
+		err = json.Unmarshal(data, &capture)
+		if err != nil {
+			return nil, fmt.Errorf("decoding capture: %w", err)
+		}
+
+		return capture.Entries, nil
+	default:
+		return nil, errUnknownCapture
+	}
+}
+
+func loadHAR(data []byte) ([]*JournalEntry, error) {
+	var har harFile
+
+	err := json.Unmarshal(data, &har)
+	if err != nil {
+		return nil, fmt.Errorf("decoding HAR: %w", err)
+	}
+
+	entries := make([]*JournalEntry, 0, len(har.Log.Entries))
+
+	for idx, harEntry := range har.Log.Entries {
+		entry, err := harEntry.journalEntry()
+		if err != nil {
+			return nil, fmt.Errorf("HAR entry #%d: %w", idx+1, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
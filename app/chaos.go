@@ -0,0 +1,150 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+// corruptRatio is the share of body bytes flipped in corrupted responses.
+const corruptRatio = 100
+
+var errChaosFaults = errors.New("network faults are not supported by proxy chaos, use errors of the endpoint instead")
+
+// proxyChaos injects faults into upstream responses.
+type proxyChaos struct {
+	cfg     *config.ProxyChaos
+	errors  *errorInjector
+	latency *latency
+	rnd     *random
+	log     *slog.Logger
+}
+
+// newProxyChaos creates new proxyChaos, returns nil if chaos is not configured.
+func newProxyChaos(cfg *config.ProxyChaos, rnd *random, log *slog.Logger) (*proxyChaos, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	if cfg.TruncateAfter < 0 {
+		return nil, fmt.Errorf("%w: %d", errTruncateAfter, cfg.TruncateAfter)
+	}
+
+	lat, err := newLatency(&config.Endpoint{Latency: cfg.Latency}, nil, rnd)
+	if err != nil {
+		return nil, err
+	}
+
+	chaos := &proxyChaos{
+		cfg:     cfg,
+		latency: lat,
+		rnd:     rnd,
+		log:     log,
+	}
+
+	if cfg.Errors != nil {
+		if len(cfg.Errors.Faults) > 0 {
+			return nil, errChaosFaults
+		}
+
+		chaos.errors, err = newErrorInjector(cfg.Errors, rnd, log)
+		if err != nil {
+			return nil, fmt.Errorf("setting up errors: %w", err)
+		}
+	}
+
+	return chaos, nil
+}
+
+// modifyResponse injects faults into the upstream response.
+func (c *proxyChaos) modifyResponse(resp *http.Response) error {
+	c.latency.wait(resp.Request.Context())
+
+	for _, name := range c.cfg.DropHeaders {
+		resp.Header.Del(name)
+	}
+
+	if outcome := c.errors.next(time.Now()); outcome != nil {
+		c.log.Debug("replacing upstream response with an error", "status", outcome.status.Status)
+
+		c.replaceWithError(resp, outcome)
+
+		return nil
+	}
+
+	corrupt := c.cfg.Corrupt > 0 && c.rnd.Float64() < float64(c.cfg.Corrupt)
+	truncate := c.cfg.Truncate > 0 && c.rnd.Float64() < float64(c.cfg.Truncate)
+
+	if !corrupt && !truncate {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		return fmt.Errorf("reading upstream response: %w", err)
+	}
+
+	if corrupt {
+		c.log.Debug("corrupting upstream response")
+		c.corrupt(body)
+	}
+
+	if truncate {
+		limit := c.cfg.TruncateAfter
+		if limit == 0 {
+			limit = len(body) / 2 //nolint:mnd // half of the body
+		}
+
+		// Content-Length of the whole body is kept, so that the client sees the connection closed prematurely,
+		// bodies not longer than the limit are sent whole, but announced a byte longer.
+		announced := len(body)
+		if limit >= len(body) {
+			limit = len(body)
+			announced = len(body) + 1
+		}
+
+		c.log.Debug("truncating upstream response", "bytes", limit)
+
+		resp.ContentLength = int64(announced)
+		resp.Header.Set("Content-Length", strconv.Itoa(announced))
+		body = body[:limit]
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return nil
+}
+
+// corrupt flips random bits of about one in a hundred bytes, at least one.
+func (c *proxyChaos) corrupt(body []byte) {
+	if len(body) == 0 {
+		return
+	}
+
+	for range max(len(body)/corruptRatio, 1) {
+		idx := c.rnd.IntN(len(body))
+		body[idx] ^= byte(1 + c.rnd.IntN(0xff)) //nolint:gosec // between 1 and 255
+	}
+}
+
+func (c *proxyChaos) replaceWithError(resp *http.Response, outcome *errorOutcome) {
+	_ = resp.Body.Close()
+
+	recorded := newBufferedResponse()
+	writeInjectedError(c.log, c.cfg.Errors, outcome, recorded)
+
+	resp.StatusCode = recorded.status
+	resp.Status = fmt.Sprintf("%d %s", recorded.status, http.StatusText(recorded.status))
+	resp.Header = recorded.header
+	resp.ContentLength = int64(recorded.body.Len())
+	resp.Body = io.NopCloser(&recorded.body)
+}
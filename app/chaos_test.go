@@ -0,0 +1,122 @@
+package app_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const upstreamBody = "the quick brown fox jumps over the lazy dog"
+
+func TestProxyChaos(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("X-Request-Id", "42")
+		writer.Header().Set("Cache-Control", "no-store")
+		_, _ = writer.Write([]byte(upstreamBody))
+	}))
+	t.Cleanup(upstream.Close)
+
+	handler := newMockHandler(t, t.TempDir(), fmt.Sprintf(`{"seed": 1, "endpoints": [
+		{"path": "/errors", "proxy": %[1]q, "proxyChaos": {
+			"errors": {"sample": 1, "statuses": [{"status": 503, "body": "chaos"}]}
+		}},
+		{"path": "/slow", "proxy": %[1]q, "proxyChaos": {"latency": {"delay": 50}, "dropHeaders": ["X-Request-Id"]}},
+		{"path": "/corrupt", "proxy": %[1]q, "proxyChaos": {"corrupt": 1}}
+	]}`, upstream.URL))
+
+	rec := serveWith(handler, "/errors", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "chaos", rec.Body.String())
+	assert.Empty(t, rec.Header().Get("X-Request-Id"))
+
+	start := time.Now()
+	rec = serveWith(handler, "/slow", nil)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(50*time.Millisecond))
+	assert.Equal(t, upstreamBody, rec.Body.String())
+	assert.Empty(t, rec.Header().Get("X-Request-Id"))
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+	rec = serveWith(handler, "/corrupt", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, rec.Body.String(), len(upstreamBody))
+	assert.NotEqual(t, upstreamBody, rec.Body.String())
+}
+
+func TestProxyChaos_Truncate(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = writer.Write([]byte(upstreamBody))
+	}))
+	t.Cleanup(upstream.Close)
+
+	server := httptest.NewServer(newMockHandler(t, t.TempDir(), fmt.Sprintf(`{"endpoints": [
+		{"path": "/truncated", "proxy": %q, "proxyChaos": {"truncate": 1, "truncateAfter": 9}}
+	]}`, upstream.URL)))
+	t.Cleanup(server.Close)
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+"/truncated", nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, int64(len(upstreamBody)), resp.ContentLength)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), err)
+	assert.Equal(t, "the quick", string(body))
+}
+
+func TestProxyChaos_TruncateShortBody(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = writer.Write([]byte(upstreamBody))
+	}))
+	t.Cleanup(upstream.Close)
+
+	server := httptest.NewServer(newMockHandler(t, t.TempDir(), fmt.Sprintf(`{"endpoints": [
+		{"path": "/truncated", "proxy": %q, "proxyChaos": {"truncate": 1, "truncateAfter": 1000}}
+	]}`, upstream.URL)))
+	t.Cleanup(server.Close)
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+"/truncated", nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, int64(len(upstreamBody)+1), resp.ContentLength)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), err)
+	assert.Equal(t, upstreamBody, string(body))
+}
+
+func TestProxyChaos_InvalidConfigSkipsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"endpoints": [
+		{"path": "/negative", "proxy": "http://localhost:1", "proxyChaos": {"truncate": 1, "truncateAfter": -1}},
+		{"path": "/faults", "proxy": "http://localhost:1", "proxyChaos": {"errors": {"faults": ["reset"]}}},
+		{"path": "/unknown", "proxy": "http://localhost:1", "proxyChaos": {"errors": {"faults": ["explode"]}}}
+	]}`)
+
+	for _, path := range []string{"/negative", "/faults", "/unknown"} {
+		assert.Equal(t, http.StatusNotFound, serveWith(handler, path, nil).Code, path)
+	}
+}
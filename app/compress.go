@@ -0,0 +1,374 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	encodingBrotli = "br"
+	encodingZstd   = "zstd"
+	encodingGzip   = "gzip"
+)
+
+var errUnsupportedEncoding = errors.New("unsupported content coding")
+
+// supportedEncodings are content codings in the default order of preference.
+var supportedEncodings = []string{encodingBrotli, encodingZstd, encodingGzip}
+
+// precompressedExtensions are extensions of precompressed files served by static endpoints.
+var precompressedExtensions = map[string]string{
+	encodingBrotli: ".br",
+	encodingGzip:   ".gz",
+}
+
+// zstdEncoders keeps zstd encoders between responses, creating one is expensive.
+var zstdEncoders sync.Pool
+
+// compressor is a streaming encoder of a content coding.
+type compressor interface {
+	io.Writer
+	Flush() error
+	Close() error
+}
+
+// compression compresses response bodies with the content coding preferred by Accept-Encoding.
+type compression struct {
+	encodings []string
+	minSize   int
+}
+
+// newCompression resolves compression of the endpoint, falling back to the global one, returns nil if not configured.
+func newCompression(endpoint, global *config.Compression) (*compression, error) {
+	cfg := endpoint
+	if cfg == nil {
+		cfg = global
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	comp := &compression{encodings: cfg.Encodings, minSize: cfg.MinSize}
+	if len(comp.encodings) == 0 {
+		comp.encodings = supportedEncodings
+	}
+
+	for _, encoding := range comp.encodings {
+		if !slices.Contains(supportedEncodings, encoding) {
+			return nil, fmt.Errorf("%w: [%s]", errUnsupportedEncoding, encoding)
+		}
+	}
+
+	return comp, nil
+}
+
+// Middleware returns the compression middleware handler.
+func (c *compression) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		addVary(writer.Header(), "Accept-Encoding")
+
+		encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"), c.encodings)
+		if encoding == "" || req.Method == http.MethodHead {
+			next.ServeHTTP(writer, req)
+
+			return
+		}
+
+		compressed := &compressedWriter{ResponseWriter: writer, compression: c, encoding: encoding}
+		defer compressed.close()
+
+		next.ServeHTTP(compressed, req)
+	})
+}
+
+// negotiateEncoding returns the content coding with the highest weight in Accept-Encoding,
+// ties go to the first of the offered ones, returns empty string for identity.
+func negotiateEncoding(header string, offered []string) string {
+	if header == "" {
+		return ""
+	}
+
+	codings := parseQualityValues(header)
+
+	var (
+		best        string
+		bestQuality float64
+	)
+
+	for _, encoding := range offered {
+		quality, found := 0.0, false
+
+		for _, coding := range codings {
+			if coding.value == encoding {
+				quality, found = coding.quality, true
+
+				break
+			}
+		}
+
+		if !found {
+			for _, coding := range codings {
+				if coding.value == "*" {
+					quality = coding.quality
+				}
+			}
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+
+	return best
+}
+
+func newCompressor(encoding string, writer io.Writer) (compressor, error) {
+	switch encoding {
+	case encodingBrotli:
+		return brotli.NewWriter(writer), nil
+	case encodingZstd:
+		if encoder, ok := zstdEncoders.Get().(*zstd.Encoder); ok {
+			encoder.Reset(writer)
+
+			return encoder, nil
+		}
+
+		encoder, err := zstd.NewWriter(writer)
+		if err != nil {
+			return nil, fmt.Errorf("creating zstd encoder: %w", err)
+		}
+
+		return encoder, nil
+	default:
+		return gzip.NewWriter(writer), nil
+	}
+}
+
+// decompress decodes the body compressed with the content encoding.
+func decompress(encoding string, data []byte) ([]byte, error) {
+	var (
+		reader io.Reader
+		err    error
+	)
+
+	switch encoding {
+	case encodingBrotli:
+		reader = brotli.NewReader(bytes.NewReader(data))
+	case encodingZstd:
+		decoder, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("creating zstd decoder: %w", err)
+		}
+
+		defer decoder.Close()
+
+		reader = decoder
+	case encodingGzip:
+		reader, err = gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("creating gzip decoder: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w [%s]", errUnsupportedEncoding, encoding)
+	}
+
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s body: %w", encoding, err)
+	}
+
+	return decoded, nil
+}
+
+// compressedWriter decides on compression when the header is written, the body is compressed
+// unless it is already encoded, empty or known to be small. Bodies are streamed, so the size is known
+// only from Content-Length, bodies without it are compressed regardless of minSize.
+type compressedWriter struct {
+	http.ResponseWriter
+
+	compression *compression
+	encoding    string
+	encoder     compressor
+	decided     bool
+}
+
+func (w *compressedWriter) WriteHeader(status int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(status)
+
+		return
+	}
+
+	w.decided = true
+
+	if w.shouldCompress(status) {
+		encoder, err := newCompressor(w.encoding, w.ResponseWriter)
+		if err == nil {
+			header := w.Header()
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+
+			// Compressed representation is not byte-for-byte the same, its validator becomes weak.
+			if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				header.Set("ETag", "W/"+etag)
+			}
+
+			w.encoder = encoder
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *compressedWriter) shouldCompress(status int) bool {
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	if size, err := strconv.Atoi(header.Get("Content-Length")); err == nil && size < max(w.compression.minSize, 1) {
+		return false
+	}
+
+	return true
+}
+
+func (w *compressedWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(data) //nolint:wrapcheck // transparent writer
+	}
+
+	return w.encoder.Write(data) //nolint:wrapcheck // transparent writer
+}
+
+func (w *compressedWriter) Flush() {
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressedWriter) close() {
+	if w.encoder == nil {
+		return
+	}
+
+	err := w.encoder.Close()
+
+	if encoder, ok := w.encoder.(*zstd.Encoder); ok && err == nil {
+		encoder.Reset(nil)
+		zstdEncoders.Put(encoder)
+	}
+}
+
+// precompressed serves precompressed variants of static files (e.g. "app.js.gz" for "app.js"),
+// if the client accepts their encoding, other requests go to the file server.
+func precompressed(root http.FileSystem, fileServer http.Handler) http.Handler {
+	offered := make([]string, 0, len(precompressedExtensions))
+	for _, encoding := range supportedEncodings {
+		if _, ok := precompressedExtensions[encoding]; ok {
+			offered = append(offered, encoding)
+		}
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		addVary(writer.Header(), "Accept-Encoding")
+
+		if strings.HasSuffix(req.URL.Path, "/") {
+			fileServer.ServeHTTP(writer, req)
+
+			return
+		}
+
+		name := path.Clean("/" + req.URL.Path)
+		remaining := offered
+
+		// Falls back to less preferred encodings, if there is no file for the preferred one.
+		for {
+			encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"), remaining)
+			if encoding == "" {
+				break
+			}
+
+			if servePrecompressed(writer, req, root, name, encoding) {
+				return
+			}
+
+			remaining = slices.DeleteFunc(slices.Clone(remaining), func(offer string) bool { return offer == encoding })
+		}
+
+		fileServer.ServeHTTP(writer, req)
+	})
+}
+
+func servePrecompressed(writer http.ResponseWriter, req *http.Request, root http.FileSystem,
+	name, encoding string) bool {
+	file, err := root.Open(name + precompressedExtensions[encoding])
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	contentType := typeByExtension(name)
+	if contentType == "" {
+		contentType = contentTypeBytes
+	}
+
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("Content-Encoding", encoding)
+
+	http.ServeContent(writer, req, name, info.ModTime(), file)
+
+	return true
+}
+
+// addVary adds the field to Vary header unless it is already there.
+func addVary(header http.Header, field string) {
+	vary := header.Get("Vary")
+	if vary == "" {
+		header.Set("Vary", field)
+
+		return
+	}
+
+	for existing := range strings.SplitSeq(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(existing), field) {
+			return
+		}
+	}
+
+	header.Set("Vary", vary+", "+field)
+}
@@ -59,3 +59,17 @@ func NewCORS(allowedOrigins ...string) *CORS {
 func (c *CORS) Middleware(next http.Handler) http.Handler {
 	return c.handler(next)
 }
+
+// endpointMiddleware returns the CORS middleware handler of the endpoint. OPTIONS requests are answered
+// by CORS without reaching next, so they are recorded as served by the endpoint here.
+func (c *CORS) endpointMiddleware(endpoint string, next http.Handler) http.Handler {
+	cors := c.handler(next)
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodOptions {
+			setAccessInfo(req, endpoint, kindPreflight)
+		}
+
+		cors.ServeHTTP(writer, req)
+	})
+}
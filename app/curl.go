@@ -0,0 +1,334 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var errCurlSyntax = errors.New("invalid cURL command")
+
+// curlValueOptions are options of curl, which take a value. Values of the options, which aren't listed in
+// parseCurl, don't matter for import, but they mustn't be taken for the URL.
+var curlValueOptions = map[string]bool{
+	"-A": true, "--user-agent": true, "-b": true, "--cookie": true, "-c": true, "--cookie-jar": true,
+	"-e": true, "--referer": true, "-o": true, "--output": true, "-u": true, "--user": true,
+	"-x": true, "--proxy": true, "-m": true, "--max-time": true, "--connect-timeout": true,
+	"--retry": true, "-w": true, "--write-out": true, "-E": true, "--cert": true, "--key": true,
+	"--cacert": true, "-T": true, "--upload-file": true, "-r": true, "--range": true,
+	"-X": true, "--request": true, "-H": true, "--header": true, "-F": true, "--form": true,
+	"--form-string": true, "-d": true, "--data": true, "--data-raw": true, "--data-binary": true,
+	"--data-ascii": true, "--data-urlencode": true, "--json": true, "--url": true,
+}
+
+// isCurlCommands tells if the text is a list of cURL commands.
+func isCurlCommands(data []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		return line == "curl" || strings.HasPrefix(line, "curl ")
+	}
+
+	return false
+}
+
+// loadCurl converts cURL commands to journal entries of requests, responses are 200 OK without a body.
+// Other commands of the list are ignored.
+func loadCurl(data []byte) ([]*JournalEntry, error) {
+	commands, err := splitCommands(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*JournalEntry
+
+	for idx, words := range commands {
+		if words[0] != "curl" {
+			continue
+		}
+
+		entry, err := parseCurl(words[1:])
+		if err != nil {
+			return nil, fmt.Errorf("command #%d: %w", idx+1, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// parseCurl converts arguments of curl to a journal entry, which has the method and URL of the request.
+func parseCurl(args []string) (*JournalEntry, error) {
+	var (
+		method, rawURL  string
+		data, get, head bool
+		positional      bool
+	)
+
+	for idx := 0; idx < len(args); idx++ {
+		option, value, next := args[idx], "", false
+
+		switch {
+		case positional || !strings.HasPrefix(option, "-") || option == "-":
+			if rawURL == "" {
+				rawURL = option
+			}
+
+			continue
+		case option == "--":
+			positional = true
+
+			continue
+		case strings.HasPrefix(option, "--"):
+			var inline bool
+
+			option, value, inline = strings.Cut(option, "=")
+			next = !inline && curlValueOptions[option]
+		case len(option) > 2 && curlValueOptions[option[:2]]:
+			// Value of a short option can follow it immediately, e.g. -XPOST.
+			option, value = option[:2], option[2:]
+		default:
+			// Short options without values can be combined, e.g. -sSL or -sI.
+			if strings.Contains(option, "I") {
+				head = true
+			}
+
+			if strings.Contains(option, "G") {
+				get = true
+			}
+
+			next = curlValueOptions["-"+option[len(option)-1:]]
+			option = "-" + option[len(option)-1:]
+		}
+
+		if next {
+			if idx+1 >= len(args) {
+				return nil, fmt.Errorf("%w: %s requires a value", errCurlSyntax, option)
+			}
+
+			idx++
+			value = args[idx]
+		}
+
+		switch option {
+		case "-X", "--request":
+			method = strings.ToUpper(value)
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii", "--data-urlencode", "--json",
+			"-F", "--form", "--form-string", "-T", "--upload-file":
+			data = true
+		case "-G", "--get":
+			get = true
+		case "-I", "--head":
+			head = true
+		case "--url":
+			rawURL = value
+		}
+	}
+
+	if rawURL == "" {
+		return nil, fmt.Errorf("%w: no URL", errCurlSyntax)
+	}
+
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+
+	reqURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing URL: %w", err)
+	}
+
+	switch {
+	case method != "":
+	case head:
+		method = http.MethodHead
+	case data && !get:
+		method = http.MethodPost
+	default:
+		method = http.MethodGet
+	}
+
+	path := reqURL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return &JournalEntry{
+		Request: RecordedRequest{
+			Method: method,
+			Host:   reqURL.Host,
+			URL:    reqURL.RequestURI(),
+			Path:   path,
+			Query:  reqURL.Query(),
+		},
+		Response: RecordedResponse{Status: http.StatusOK},
+	}, nil
+}
+
+// splitCommands splits shell text to commands of words. It supports quotes, ANSI-C quotes ($'...'),
+// escapes, line continuations, comments and command separators, which is enough for commands copied
+// from browsers and documentation.
+func splitCommands(text string) ([][]string, error) {
+	var (
+		commands [][]string
+		words    []string
+		word     strings.Builder
+		inWord   bool
+	)
+
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	endCommand := func() {
+		endWord()
+
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	for idx := 0; idx < len(text); idx++ {
+		char := text[idx]
+
+		switch {
+		case char == '\\' && idx+1 < len(text):
+			idx++
+
+			if text[idx] == '\r' && idx+1 < len(text) && text[idx+1] == '\n' {
+				idx++
+			}
+
+			if text[idx] != '\n' {
+				word.WriteByte(text[idx])
+				inWord = true
+			}
+		case char == '\'':
+			end := strings.IndexByte(text[idx+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated quote", errCurlSyntax)
+			}
+
+			word.WriteString(text[idx+1 : idx+1+end])
+			inWord = true
+			idx += end + 1
+		case char == '$' && idx+1 < len(text) && text[idx+1] == '\'':
+			end, err := unquoteANSI(text[idx+2:], &word)
+			if err != nil {
+				return nil, err
+			}
+
+			inWord = true
+			idx += end + 2
+		case char == '"':
+			end, err := unquoteDouble(text[idx+1:], &word)
+			if err != nil {
+				return nil, err
+			}
+
+			inWord = true
+			idx += end + 1
+		case char == '#' && !inWord:
+			end := strings.IndexByte(text[idx:], '\n')
+			if end < 0 {
+				end = len(text) - idx
+			}
+
+			idx += end - 1
+		case char == '\n' || char == ';' || char == '&' || char == '|':
+			endCommand()
+		case char == ' ' || char == '\t' || char == '\r':
+			endWord()
+		default:
+			word.WriteByte(char)
+			inWord = true
+		}
+	}
+
+	endCommand()
+
+	return commands, nil
+}
+
+// unquoteDouble writes the double quoted text up to the closing quote, returns the index of the quote.
+func unquoteDouble(text string, word *strings.Builder) (int, error) {
+	for idx := 0; idx < len(text); idx++ {
+		switch {
+		case text[idx] == '"':
+			return idx, nil
+		case text[idx] == '\\' && idx+1 < len(text) && strings.IndexByte("\"\\$`\n", text[idx+1]) >= 0:
+			idx++
+
+			if text[idx] != '\n' {
+				word.WriteByte(text[idx])
+			}
+		default:
+			word.WriteByte(text[idx])
+		}
+	}
+
+	return 0, fmt.Errorf("%w: unterminated quote", errCurlSyntax)
+}
+
+// unquoteANSI writes the ANSI-C quoted text up to the closing quote, returns the index of the quote.
+func unquoteANSI(text string, word *strings.Builder) (int, error) {
+	escapes := map[byte]string{'n': "\n", 't': "\t", 'r': "\r", '\\': "\\", '\'': "'", '"': "\""}
+
+	for idx := 0; idx < len(text); idx++ {
+		if text[idx] == '\'' {
+			return idx, nil
+		}
+
+		if text[idx] != '\\' || idx+1 >= len(text) {
+			word.WriteByte(text[idx])
+
+			continue
+		}
+
+		idx++
+
+		if escaped, ok := escapes[text[idx]]; ok {
+			word.WriteString(escaped)
+
+			continue
+		}
+
+		// Hexadecimal escapes, \xHH and \uHHHH, other escapes are kept as they are.
+		digits := map[byte]int{'x': 2, 'u': 4}[text[idx]]
+		if digits > 0 && idx+digits < len(text) {
+			code, err := strconv.ParseUint(text[idx+1:idx+1+digits], 16, 32)
+			if err == nil {
+				if text[idx] == 'x' {
+					word.WriteByte(byte(code)) //nolint:gosec // two hexadecimal digits
+				} else {
+					word.WriteRune(rune(code)) //nolint:gosec // four hexadecimal digits
+				}
+
+				idx += digits
+
+				continue
+			}
+		}
+
+		word.WriteByte('\\')
+		word.WriteByte(text[idx])
+	}
+
+	return 0, fmt.Errorf("%w: unterminated quote", errCurlSyntax)
+}
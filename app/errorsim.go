@@ -0,0 +1,248 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	errorModeNth    = "nth"
+	errorModeRandom = "random"
+
+	defaultErrorBody = "failed with predefined error"
+)
+
+var (
+	errUnknownFault  = errors.New("unknown fault")
+	errTruncateAfter = errors.New("truncateAfter can't be negative")
+	errErrorMode     = errors.New("unknown error mode")
+	errErrorWindow   = errors.New("invalid error window")
+)
+
+// errorOutcome is an injected error, either an HTTP status or a network fault.
+type errorOutcome struct {
+	status config.ErrorStatus
+	fault  string
+	weight int
+}
+
+// errorInjector decides which requests fail and how.
+type errorInjector struct {
+	cfg         *config.Errors
+	rnd         *random
+	every       uint64
+	outcomes    []errorOutcome
+	totalWeight int
+
+	start          time.Time
+	windowEvery    time.Duration
+	windowDuration time.Duration
+	windowOffset   time.Duration
+
+	lock      sync.Mutex
+	ops       uint64
+	burstLeft int
+}
+
+// newErrorInjector creates new errorInjector, returns nil if errors are not configured.
+func newErrorInjector(cfg *config.Errors, rnd *random, log *slog.Logger) (*errorInjector, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	if cfg.TruncateAfter < 0 {
+		return nil, fmt.Errorf("%w: %d", errTruncateAfter, cfg.TruncateAfter)
+	}
+
+	switch cfg.Mode {
+	case "", errorModeNth, errorModeRandom:
+	default:
+		return nil, fmt.Errorf("%w [%s]", errErrorMode, cfg.Mode)
+	}
+
+	inj := &errorInjector{
+		cfg:   cfg,
+		rnd:   rnd,
+		start: time.Now(),
+	}
+
+	if cfg.Sample > 0 {
+		inj.every = uint64(1.0 / cfg.Sample)
+	}
+
+	for _, status := range cfg.Statuses {
+		if status.Status <= 0 {
+			status.Status = http.StatusInternalServerError
+		}
+
+		inj.outcomes = append(inj.outcomes, errorOutcome{status: status, weight: max(status.Weight, 1)})
+	}
+
+	for _, fault := range cfg.Faults {
+		switch fault {
+		case faultReset, faultEmpty, faultGarbage, faultTruncate, faultStall:
+		default:
+			return nil, fmt.Errorf("%w [%s]", errUnknownFault, fault)
+		}
+
+		inj.outcomes = append(inj.outcomes, errorOutcome{fault: fault, weight: 1})
+	}
+
+	if len(inj.outcomes) == 0 {
+		inj.outcomes = []errorOutcome{{status: config.ErrorStatus{Status: http.StatusInternalServerError}, weight: 1}}
+	}
+
+	for _, outcome := range inj.outcomes {
+		inj.totalWeight += outcome.weight
+	}
+
+	err := inj.setupWindow()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("errors will be injected",
+		"mode", cfg.Mode,
+		"every_nth_err", inj.every,
+		"burst", cfg.Burst,
+		"outcomes", len(inj.outcomes))
+
+	return inj, nil
+}
+
+func (e *errorInjector) setupWindow() error {
+	if e.cfg.Window == nil {
+		return nil
+	}
+
+	every, err := time.ParseDuration(e.cfg.Window.Every)
+	if err != nil || every <= 0 {
+		return fmt.Errorf("%w: every [%s]", errErrorWindow, e.cfg.Window.Every)
+	}
+
+	duration, err := time.ParseDuration(e.cfg.Window.Duration)
+	if err != nil || duration < 0 {
+		return fmt.Errorf("%w: duration [%s]", errErrorWindow, e.cfg.Window.Duration)
+	}
+
+	// Offset is optional.
+	var offset time.Duration
+
+	if e.cfg.Window.Offset != "" {
+		offset, err = time.ParseDuration(e.cfg.Window.Offset)
+		if err != nil {
+			return fmt.Errorf("%w: offset [%s]", errErrorWindow, e.cfg.Window.Offset)
+		}
+	}
+
+	e.windowEvery = every
+	e.windowDuration = duration
+	e.windowOffset = offset
+
+	return nil
+}
+
+// next returns an error to inject into the current request or nil.
+func (e *errorInjector) next(now time.Time) *errorOutcome {
+	if e == nil || !e.triggered(now) {
+		return nil
+	}
+
+	pick := e.rnd.IntN(e.totalWeight)
+
+	for idx := range e.outcomes {
+		pick -= e.outcomes[idx].weight
+		if pick < 0 {
+			return &e.outcomes[idx]
+		}
+	}
+
+	return nil
+}
+
+func (e *errorInjector) triggered(now time.Time) bool {
+	if e.inWindow(now) {
+		return true
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.burstLeft > 0 {
+		e.burstLeft--
+
+		return true
+	}
+
+	var hit bool
+
+	switch e.cfg.Mode {
+	case errorModeRandom:
+		hit = e.rnd.Float64() < float64(e.cfg.Sample)
+	default: // errorModeNth
+		e.ops++
+		if e.every > 0 && e.ops >= e.every {
+			e.ops = 0
+			hit = true
+		}
+	}
+
+	if hit && e.cfg.Burst > 1 {
+		e.burstLeft = e.cfg.Burst - 1
+	}
+
+	return hit
+}
+
+func (e *errorInjector) inWindow(now time.Time) bool {
+	if e.windowEvery <= 0 {
+		return false
+	}
+
+	elapsed := now.Sub(e.start) - e.windowOffset
+	if elapsed < 0 {
+		return false
+	}
+
+	return elapsed%e.windowEvery < e.windowDuration
+}
+
+// writeInjectedError writes error response of the outcome with configured body and headers.
+func writeInjectedError(log *slog.Logger, cfg *config.Errors, outcome *errorOutcome, writer http.ResponseWriter) {
+	header := writer.Header()
+
+	for name, value := range cfg.Headers {
+		header.Set(name, value)
+	}
+
+	for name, value := range outcome.status.Headers {
+		header.Set(name, value)
+	}
+
+	body := outcome.status.Body
+	if body == "" {
+		body = cfg.Body
+	}
+
+	if body == "" {
+		body = defaultErrorBody + "\n"
+	}
+
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	log.Debug("injecting error", "status", outcome.status.Status)
+
+	writer.WriteHeader(outcome.status.Status)
+	_, _ = writer.Write([]byte(body))
+}
@@ -0,0 +1,122 @@
+package app //nolint:testpackage // testing unexported error injection internals
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+func newTestInjector(t *testing.T, cfg *config.Errors, seed int64) *errorInjector {
+	t.Helper()
+
+	inj, err := newErrorInjector(cfg, newRandom(seed, 0), slog.Default())
+	require.NoError(t, err)
+
+	return inj
+}
+
+func failures(inj *errorInjector, requests int, now time.Time) []bool {
+	result := make([]bool, requests)
+	for idx := range result {
+		result[idx] = inj.next(now) != nil
+	}
+
+	return result
+}
+
+func Test_ErrorInjector_EveryNthWithBurst(t *testing.T) {
+	t.Parallel()
+
+	inj := newTestInjector(t, &config.Errors{Sample: 0.25, Burst: 2}, 1)
+
+	assert.Equal(t,
+		[]bool{false, false, false, true, true, false, false, false, true},
+		failures(inj, 9, time.Now()))
+}
+
+func Test_ErrorInjector_RandomIsReproducible(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Errors{Sample: 0.3, Mode: errorModeRandom}
+	first := failures(newTestInjector(t, cfg, 5), 100, time.Now())
+	second := failures(newTestInjector(t, cfg, 5), 100, time.Now())
+
+	assert.Equal(t, first, second)
+	assert.Contains(t, first, true)
+	assert.Contains(t, first, false)
+}
+
+func Test_ErrorInjector_Window(t *testing.T) {
+	t.Parallel()
+
+	inj := newTestInjector(t, &config.Errors{
+		Window: &config.ErrorWindow{Every: "5m", Duration: "30s"},
+	}, 1)
+
+	assert.NotNil(t, inj.next(inj.start.Add(10*time.Second)))
+	assert.Nil(t, inj.next(inj.start.Add(time.Minute)))
+	assert.NotNil(t, inj.next(inj.start.Add(5*time.Minute+time.Second)))
+}
+
+func Test_ErrorInjector_InvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	for name, test := range map[string]struct {
+		cfg *config.Errors
+		err error
+	}{
+		"unknown mode":    {&config.Errors{Sample: 0.5, Mode: "sometimes"}, errErrorMode},
+		"invalid every":   {&config.Errors{Window: &config.ErrorWindow{Every: "5", Duration: "30s"}}, errErrorWindow},
+		"zero every":      {&config.Errors{Window: &config.ErrorWindow{Every: "0s", Duration: "30s"}}, errErrorWindow},
+		"invalid length":  {&config.Errors{Window: &config.ErrorWindow{Every: "5m", Duration: "half"}}, errErrorWindow},
+		"negative length": {&config.Errors{Window: &config.ErrorWindow{Every: "5m", Duration: "-1s"}}, errErrorWindow},
+		"invalid offset": {
+			&config.Errors{Window: &config.ErrorWindow{Every: "5m", Duration: "30s", Offset: "1 minute"}},
+			errErrorWindow,
+		},
+	} {
+		inj, err := newErrorInjector(test.cfg, newRandom(1, 0), slog.Default())
+		assert.True(t, errors.Is(err, test.err), name, err)
+		assert.Nil(t, inj, name)
+	}
+
+	newTestInjector(t, &config.Errors{Sample: 0.5, Mode: errorModeNth}, 1)
+}
+
+func Test_ErrorInjector_WeightsAndResponse(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Errors{
+		Sample:  1,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    `{"error":"unavailable"}`,
+		Statuses: []config.ErrorStatus{
+			{Status: http.StatusInternalServerError},
+			{Status: http.StatusServiceUnavailable, Weight: 9, Headers: map[string]string{"Retry-After": "30"}},
+		},
+	}
+	inj := newTestInjector(t, cfg, 3)
+
+	counts := map[int]int{}
+	for range 1000 {
+		counts[inj.next(time.Now()).status.Status]++
+	}
+
+	assert.InDelta(t, 900, counts[http.StatusServiceUnavailable], 50)
+
+	rec := httptest.NewRecorder()
+	writeInjectedError(slog.Default(), cfg, &inj.outcomes[1], rec)
+
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"error":"unavailable"}`, rec.Body.String())
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	kindFallback = "fallback"
+
+	// nearestEndpoints is the number of endpoints listed by diagnostic fallback responses.
+	nearestEndpoints = 3
+)
+
+// fallback serves requests, which match none of the endpoints.
+type fallback struct {
+	cfg       *config.Fallback
+	endpoints []*config.Endpoint
+	body      *responseBody
+	headers   *responseHeaders
+	proxy     *Proxy
+}
+
+// setupFallback replaces default 404 and 405 responses of the router with the configured fallback.
+func setupFallback(cfg *config.Config, mockPath string, mck *config.Mock, router *chi.Mux) {
+	if mck.Fallback == nil {
+		return
+	}
+
+	logger := slog.Default().With("endpoint", "fallback")
+
+	fbk, err := newFallback(cfg, mockPath, mck, logger)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error in setting up fallback: %v", err))
+
+		return
+	}
+
+	router.NotFound(fbk.handler(http.StatusNotFound))
+	router.MethodNotAllowed(fbk.handler(http.StatusMethodNotAllowed))
+}
+
+func newFallback(cfg *config.Config, mockPath string, mck *config.Mock, logger *slog.Logger) (*fallback, error) {
+	fbk := &fallback{cfg: mck.Fallback, endpoints: mck.Endpoints}
+
+	if fbk.cfg.Proxy != "" {
+		transport, err := newProxyTransport(mockPath, fbk.cfg.ProxyOptions, mck.ProxyOptions)
+		if err != nil {
+			return nil, fmt.Errorf("setting up proxy options: %w", err)
+		}
+
+		proxy, err := newProxy(cfg.Server.Addr, fbk.cfg.Proxy, fbk.cfg.ProxyRules, transport, logger)
+		if err != nil {
+			return nil, fmt.Errorf("creating a proxy: %w", err)
+		}
+
+		fbk.proxy = proxy
+
+		fbk.proxy.chaos, err = newProxyChaos(fbk.cfg.ProxyChaos, newRandom(mck.Seed, uint64(len(mck.Endpoints))), logger)
+		if err != nil {
+			return nil, fmt.Errorf("setting up proxy chaos: %w", err)
+		}
+
+		return fbk, nil
+	}
+
+	endpoint := &config.Endpoint{JSON: fbk.cfg.JSON, Body: fbk.cfg.Body, Headers: fbk.cfg.Headers}
+
+	body, err := loadBody(mockPath, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := newResponseHeaders(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	fbk.body = body
+	fbk.headers = headers
+
+	return fbk, nil
+}
+
+func (f *fallback) handler(defaultStatus int) http.HandlerFunc {
+	status := f.cfg.Status
+	if status <= 0 {
+		status = defaultStatus
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		setAccessInfo(req, "", kindFallback)
+
+		if f.proxy != nil {
+			// Allow header of unsupported methods is meaningless for the upstream's response.
+			writer.Header().Del("Allow")
+			f.proxy.ServeHTTP(writer, req)
+
+			return
+		}
+
+		if f.cfg.Diagnostic {
+			setContentType(writer, contentTypeJSON)
+			writer.WriteHeader(status)
+
+			_ = writeResponse(writer, f.diagnose(req))
+
+			return
+		}
+
+		var data *templateData
+		if f.headers != nil || f.body.templated() {
+			data = newTemplateData(req)
+		}
+
+		f.headers.apply(writer, data)
+
+		if f.body == nil {
+			writer.WriteHeader(status)
+
+			return
+		}
+
+		setContentType(writer, f.body.contentType)
+		writer.WriteHeader(status)
+
+		_, _ = writer.Write(f.body.render(data))
+	}
+}
+
+// diagnosis describes an unmatched request and the endpoints nearest to it.
+type diagnosis struct {
+	Error   string          `json:"error"`
+	Method  string          `json:"method"`
+	Path    string          `json:"path"`
+	Nearest []*nearEndpoint `json:"nearest"`
+}
+
+type nearEndpoint struct {
+	Methods  []string `json:"methods,omitempty"`
+	Path     string   `json:"path"`
+	Distance int      `json:"distance"`         // edit distance between the paths, 0 if the path matches
+	Reason   string   `json:"reason,omitempty"` // why an endpoint with the matching path didn't serve the request
+}
+
+func (f *fallback) diagnose(req *http.Request) *diagnosis {
+	nearest := make([]*nearEndpoint, 0, len(f.endpoints))
+
+	for _, endpoint := range f.endpoints {
+		methods := endpoint.Methods
+		if len(methods) == 0 && endpoint.Static == "" {
+			methods = []string{http.MethodGet}
+		}
+
+		near := &nearEndpoint{Methods: methods, Path: endpoint.Path}
+
+		switch {
+		case !RouteMatches(endpoint.Path, req.URL.Path):
+			near.Distance = editDistance(endpoint.Path, req.URL.Path)
+		case len(methods) > 0 && !slices.Contains(methods, req.Method):
+			near.Reason = "method is not allowed"
+		default:
+			near.Reason = "match conditions are not met"
+		}
+
+		nearest = append(nearest, near)
+	}
+
+	slices.SortStableFunc(nearest, func(a, b *nearEndpoint) int {
+		return a.Distance - b.Distance
+	})
+
+	return &diagnosis{
+		Error:   "no endpoint matches the request",
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Nearest: nearest[:min(len(nearest), nearestEndpoints)],
+	}
+}
+
+// editDistance is the Levenshtein distance between the strings.
+func editDistance(left, right string) int {
+	previous := make([]int, len(right)+1)
+	current := make([]int, len(right)+1)
+
+	for idx := range previous {
+		previous[idx] = idx
+	}
+
+	for i := 1; i <= len(left); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(right); j++ {
+			cost := 1
+			if left[i-1] == right[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(right)]
+}
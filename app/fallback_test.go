@@ -0,0 +1,119 @@
+package app_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallback_CustomResponse(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{
+		"fallback": {"status": 418, "body": "no {{.Path}} here", "headers": {"X-Fallback": "{{.Method}}"}},
+		"endpoints": [{"methods": ["GET"], "path": "/users", "body": "users"}]
+	}`)
+
+	rec := serveWith(handler, "/orders", nil)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Equal(t, "no /orders here", rec.Body.String())
+	assert.Equal(t, http.MethodGet, rec.Header().Get("X-Fallback"))
+
+	rec = serveWith(handler, "/users", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "users", rec.Body.String())
+}
+
+func TestFallback_DefaultStatus(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{
+		"fallback": {"json": {"error": "not mocked"}},
+		"endpoints": [{"methods": ["GET"], "path": "/users", "body": "users"}]
+	}`)
+
+	rec := serveWith(handler, "/orders", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error": "not mocked"}`, rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodDelete, "/users", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.JSONEq(t, `{"error": "not mocked"}`, rec.Body.String())
+}
+
+func TestFallback_Proxy(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		_, _ = fmt.Fprintf(writer, "upstream %s %s", req.Method, req.URL.Path)
+	}))
+	t.Cleanup(upstream.Close)
+
+	handler := newMockHandler(t, t.TempDir(), fmt.Sprintf(`{
+		"fallback": {"proxy": %q},
+		"endpoints": [{"methods": ["GET"], "path": "/users", "body": "mocked"}]
+	}`, upstream.URL))
+
+	rec := serveWith(handler, "/users", nil)
+	assert.Equal(t, "mocked", rec.Body.String())
+
+	rec = serveWith(handler, "/orders", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "upstream GET /orders", rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "upstream POST /users", rec.Body.String())
+	assert.Empty(t, rec.Header().Get("Allow"))
+}
+
+func TestFallback_Diagnostic(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{
+		"fallback": {"diagnostic": true},
+		"endpoints": [
+			{"methods": ["GET"], "path": "/users/{id}", "body": "user",
+			 "match": {"headers": {"X-Tenant": "acme"}}},
+			{"methods": ["POST"], "path": "/users", "status": 201},
+			{"methods": ["GET"], "path": "/orders"},
+			{"methods": ["GET"], "path": "/healthz/details"}
+		]
+	}`)
+
+	rec := serveWith(handler, "/users/1", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	var diagnosis struct {
+		Error   string `json:"error"`
+		Method  string `json:"method"`
+		Path    string `json:"path"`
+		Nearest []struct {
+			Methods  []string `json:"methods"`
+			Path     string   `json:"path"`
+			Distance int      `json:"distance"`
+			Reason   string   `json:"reason"`
+		} `json:"nearest"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &diagnosis))
+
+	assert.Equal(t, "no endpoint matches the request", diagnosis.Error)
+	assert.Equal(t, "/users/1", diagnosis.Path)
+	require.Len(t, diagnosis.Nearest, 3)
+
+	assert.Equal(t, "/users/{id}", diagnosis.Nearest[0].Path)
+	assert.Equal(t, 0, diagnosis.Nearest[0].Distance)
+	assert.Equal(t, "match conditions are not met", diagnosis.Nearest[0].Reason)
+	assert.Equal(t, "/users", diagnosis.Nearest[1].Path)
+	assert.Equal(t, 2, diagnosis.Nearest[1].Distance)
+	assert.Equal(t, "/orders", diagnosis.Nearest[2].Path)
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Network level faults, which break the transport instead of returning an HTTP error.
+const (
+	faultReset    = "reset"    // abrupt TCP reset after reading the request
+	faultEmpty    = "empty"    // connection closed without any response
+	faultGarbage  = "garbage"  // random bytes instead of HTTP
+	faultTruncate = "truncate" // body cut off despite a larger Content-Length
+	faultStall    = "stall"    // headers sent, then nothing until the client gives up
+
+	garbageSize = 64
+)
+
+// injectFault breaks the connection of the request in the way described by fault,
+// serve is used to produce the original response for faults which send a part of it.
+func injectFault(log *slog.Logger, fault string, truncateAfter int, writer http.ResponseWriter,
+	req *http.Request, serve func(http.ResponseWriter, *http.Request) *appError) *appError {
+	var recorded *bufferedResponse
+
+	if fault == faultTruncate || fault == faultStall {
+		recorded = newBufferedResponse()
+
+		appErr := serve(recorded, req)
+		if appErr != nil {
+			return appErr
+		}
+	}
+
+	_, _ = io.Copy(io.Discard, req.Body)
+
+	conn, bufrw, err := http.NewResponseController(writer).Hijack()
+	if err != nil {
+		// Connection can't be hijacked (e.g. HTTP/2), aborting the handler at least breaks the stream.
+		log.Debug("hijacking is not supported, aborting the request", "fault", fault, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.SetDeadline(time.Time{})
+
+	switch fault {
+	case faultReset:
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			_ = tcpConn.SetLinger(0)
+		}
+	case faultGarbage:
+		garbage := make([]byte, garbageSize)
+		_, _ = rand.Read(garbage)
+		_, _ = conn.Write(garbage)
+	case faultTruncate:
+		body := recorded.body.Bytes()
+
+		limit := truncateAfter
+		if limit == 0 {
+			limit = len(body) / 2 //nolint:mnd // half of the body
+		}
+
+		// Bodies not longer than the limit are sent whole, but announced a byte longer, so that the client
+		// still sees the connection closed prematurely.
+		announced := len(body)
+		if limit >= len(body) {
+			limit = len(body)
+			announced = len(body) + 1
+		}
+
+		recorded.writeHead(bufrw.Writer, announced)
+		_, _ = bufrw.Write(body[:limit])
+		_ = bufrw.Flush()
+	case faultStall:
+		recorded.writeHead(bufrw.Writer, recorded.body.Len())
+		_ = bufrw.Flush()
+
+		// Hold the connection until the client gives up.
+		_, _ = io.Copy(io.Discard, bufrw)
+	}
+
+	return nil
+}
+
+// bufferedResponse keeps the whole response in memory.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{
+		header: http.Header{},
+		status: http.StatusOK,
+	}
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	b.status = code
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	return b.body.Write(data) //nolint:wrapcheck // in-memory buffer
+}
+
+// writeHead writes raw HTTP/1.1 status line and headers announcing given content length.
+func (b *bufferedResponse) writeHead(writer *bufio.Writer, contentLength int) {
+	header := b.header.Clone()
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", strconv.Itoa(contentLength))
+
+	_, _ = fmt.Fprintf(writer, "HTTP/1.1 %d %s\r\n", b.status, http.StatusText(b.status))
+	_ = header.Write(writer)
+	_, _ = writer.WriteString("\r\n")
+}
@@ -0,0 +1,133 @@
+package app_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock/app"
+	"github.com/smeshkov/gomock/config"
+)
+
+func newFaultServer(t *testing.T, fault string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(app.RegisterHandlers("test", ".", &config.Config{}, &config.Mock{
+		Endpoints: []*config.Endpoint{
+			{
+				Methods: []string{http.MethodGet},
+				Path:    "/fault",
+				JSON:    map[string]any{"message": "a response long enough to be truncated"},
+				Errors:  &config.Errors{Sample: 1, Faults: []string{fault}, TruncateAfter: 5},
+			},
+		},
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestFaults_BreakTransport(t *testing.T) {
+	t.Parallel()
+
+	for _, fault := range []string{"reset", "empty", "garbage"} {
+		t.Run(fault, func(t *testing.T) {
+			t.Parallel()
+
+			srv := newFaultServer(t, fault)
+
+			resp, err := srv.Client().Get(srv.URL + "/fault")
+			if resp != nil {
+				_ = resp.Body.Close()
+			}
+
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestFaults_Truncate(t *testing.T) {
+	t.Parallel()
+
+	srv := newFaultServer(t, "truncate")
+
+	resp, err := srv.Client().Get(srv.URL + "/fault")
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Greater(t, resp.ContentLength, int64(5))
+
+	body, err := io.ReadAll(resp.Body)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), err)
+	assert.Equal(t, `{"mes`, string(body))
+}
+
+func TestFaults_TruncateShortBody(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(app.RegisterHandlers("test", ".", &config.Config{}, &config.Mock{
+		Endpoints: []*config.Endpoint{
+			{
+				Methods: []string{http.MethodGet},
+				Path:    "/fault",
+				JSON:    map[string]any{"ok": true},
+				Errors:  &config.Errors{Sample: 1, Faults: []string{"truncate"}, TruncateAfter: 1000},
+			},
+		},
+	}))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/fault")
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), err)
+	assert.JSONEq(t, `{"ok":true}`, string(body))
+	assert.Equal(t, int64(len(body)+1), resp.ContentLength)
+}
+
+func TestFaults_Stall(t *testing.T) {
+	t.Parallel()
+
+	srv := newFaultServer(t, "stall")
+
+	client := srv.Client()
+	client.Timeout = 200 * time.Millisecond
+
+	resp, err := client.Get(srv.URL + "/fault")
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = io.ReadAll(resp.Body)
+	require.Error(t, err)
+}
+
+func TestFaults_InvalidConfigSkipsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	for name, errs := range map[string]*config.Errors{
+		"unknown fault":           {Sample: 1, Faults: []string{"explode"}},
+		"negative truncate after": {Sample: 1, Faults: []string{"truncate"}, TruncateAfter: -1},
+	} {
+		handler := app.RegisterHandlers("test", ".", &config.Config{}, &config.Mock{
+			Endpoints: []*config.Endpoint{{Path: "/fault", JSON: map[string]any{}, Errors: errs}},
+		})
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fault", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code, name)
+	}
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+const (
+	contentTypeForm      = "application/x-www-form-urlencoded"
+	contentTypeMultipart = "multipart/form-data"
+
+	// maxFormMemory is the size of multipart files kept in memory while parsing, the rest goes to temporary files.
+	maxFormMemory = 32 << 20
+)
+
+// requestForm is a parsed urlencoded or multipart body of a request.
+type requestForm struct {
+	values    url.Values
+	multipart *multipart.Form
+}
+
+// isFormRequest tells if the request has urlencoded or multipart body.
+func isFormRequest(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	return mediaType == contentTypeForm || mediaType == contentTypeMultipart
+}
+
+// readRequestForm parses form body of the request and puts the body back, so that it can be read again.
+// Returns nil if the request has no form body, parsed form has to be closed.
+func readRequestForm(req *http.Request) (*requestForm, error) {
+	if !isFormRequest(req) {
+		return nil, nil
+	}
+
+	data, err := peekBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(data))
+	clone.Form, clone.PostForm, clone.MultipartForm = nil, nil, nil
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == contentTypeMultipart {
+		err = clone.ParseMultipartForm(maxFormMemory)
+		if err != nil {
+			return nil, fmt.Errorf("parsing multipart form: %w", err)
+		}
+
+		return &requestForm{values: clone.MultipartForm.Value, multipart: clone.MultipartForm}, nil
+	}
+
+	err = clone.ParseForm()
+	if err != nil {
+		return nil, fmt.Errorf("parsing form: %w", err)
+	}
+
+	return &requestForm{values: clone.PostForm}, nil
+}
+
+// fields converts values of the form into an object, fields with several values become arrays.
+func (f *requestForm) fields() map[string]any {
+	obj := make(map[string]any, len(f.values))
+
+	for key, values := range f.values {
+		if len(values) == 1 {
+			obj[key] = values[0]
+
+			continue
+		}
+
+		list := make([]any, 0, len(values))
+		for _, value := range values {
+			list = append(list, value)
+		}
+
+		obj[key] = list
+	}
+
+	return obj
+}
+
+// files returns uploaded files of the field or of all fields if the field is empty.
+func (f *requestForm) files(field string) []*multipart.FileHeader {
+	if f == nil || f.multipart == nil {
+		return nil
+	}
+
+	if field != "" {
+		return f.multipart.File[field]
+	}
+
+	var files []*multipart.FileHeader
+	for _, name := range slices.Sorted(maps.Keys(f.multipart.File)) {
+		files = append(files, f.multipart.File[name]...)
+	}
+
+	return files
+}
+
+// close removes temporary files of multipart form.
+func (f *requestForm) close() {
+	if f == nil || f.multipart == nil {
+		return
+	}
+
+	_ = f.multipart.RemoveAll()
+}
+
+// readRequestInput reads JSON or form body of the request into an object.
+func readRequestInput(req *http.Request) (map[string]any, *requestForm, *appError) {
+	if !isFormRequest(req) {
+		input := map[string]any{}
+
+		appErr := readRequestJSON(req.Context(), req, &input)
+		if appErr != nil {
+			return nil, nil, appErr
+		}
+
+		return input, nil, nil
+	}
+
+	form, err := readRequestForm(req)
+	if err != nil {
+		return nil, nil, &appError{
+			Error:   err,
+			Message: fmt.Sprintf("wrong request body: %v", err),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	return form.fields(), form, nil
+}
@@ -0,0 +1,201 @@
+package app_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock/app"
+	"github.com/smeshkov/gomock/config"
+)
+
+func newMockHandler(t *testing.T, dir, mock string) http.Handler {
+	t.Helper()
+
+	var mck config.Mock
+	require.NoError(t, json.Unmarshal([]byte(mock), &mck))
+
+	return app.RegisterHandlers("test", dir, &config.Config{}, &mck)
+}
+
+func multipartBody(t *testing.T, fields map[string]string, field, name, content string) (*bytes.Buffer, string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	writer := multipart.NewWriter(&buf)
+
+	for key, value := range fields {
+		require.NoError(t, writer.WriteField(key, value))
+	}
+
+	part, err := writer.CreateFormFile(field, name)
+	require.NoError(t, err)
+
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	return &buf, writer.FormDataContentType()
+}
+
+func TestForm_URLEncodedWriteMatchAndTemplate(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"endpoints": [
+		{"methods": ["POST"], "path": "/login", "match": {"form": {"user": "admin"}},
+		 "body": "welcome {{.Form.user}}"},
+		{"methods": ["POST"], "path": "/login", "status": 401, "body": "denied"},
+		{"methods": ["POST"], "path": "/notes", "status": 201,
+		 "dynamic": {"write": {"json": {"name": "note", "key": "id", "value": "."}}}},
+		{"path": "/notes/{id}", "dynamic": {"read": {"json": {"name": "note", "keyParam": "id"}}}}
+	]}`)
+
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := post("/login", url.Values{"user": {"admin"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "welcome admin", rec.Body.String())
+
+	rec = post("/login", url.Values{"user": {"guest"}})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = post("/notes", url.Values{"id": {"1"}, "tag": {"a", "b"}})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/notes/1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id": "1", "tag": ["a", "b"]}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/notes/2", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestForm_UploadAndDownload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	handler := newMockHandler(t, dir, `{"endpoints": [
+		{"methods": ["POST"], "path": "/avatars", "status": 201,
+		 "dynamic": {"write": {"files": {"name": "avatar", "field": "file", "key": "user"}}}},
+		{"path": "/avatars/{user}", "dynamic": {"read": {"file": {"name": "avatar", "keyParam": "user"}}}},
+		{"methods": ["POST"], "path": "/docs", "status": 201,
+		 "dynamic": {"write": {"files": {"name": "doc", "key": "path", "dir": "docs"}}}},
+		{"methods": ["POST"], "path": "/imports", "status": 202,
+		 "dynamic": {"write": {"files": {"name": "import", "dir": "uploads"}}}},
+		{"path": "/imports/{name}", "dynamic": {"read": {"file": {"name": "import", "keyParam": "name"}}}},
+		{"path": "/imports", "dynamic": {"read": {"json": {"name": "import"}}}}
+	]}`)
+
+	upload := func(path string, fields map[string]string, field, name, content string) *httptest.ResponseRecorder {
+		body, contentType := multipartBody(t, fields, field, name, content)
+
+		req := httptest.NewRequest(http.MethodPost, path, body)
+		req.Header.Set("Content-Type", contentType)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return rec
+	}
+
+	// Kept in the store by the "user" field.
+	rec := upload("/avatars", map[string]string{"user": "42"}, "file", "me.png", "\x89PNG\r\n\x1a\n")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = get("/avatars/42")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=me.png", rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "\x89PNG\r\n\x1a\n", rec.Body.String())
+
+	// Non-ASCII names are encoded, so that clients decode them back.
+	rec = upload("/avatars", map[string]string{"user": "43"}, "file", "résumé.png", "\x89PNG\r\n\x1a\n")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	_, params, err := mime.ParseMediaType(get("/avatars/43").Header().Get("Content-Disposition"))
+	require.NoError(t, err)
+	assert.Equal(t, "résumé.png", params["filename"])
+
+	assert.Equal(t, http.StatusNotFound, get("/avatars/7").Code)
+
+	// Missing file.
+	rec = upload("/avatars", map[string]string{"user": "42"}, "other", "me.png", "x")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Only one file can be stored under a key from the form.
+	var body bytes.Buffer
+
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("user", "42"))
+
+	for _, name := range []string{"a.png", "b.png"} {
+		part, err := writer.CreateFormFile("file", name)
+		require.NoError(t, err)
+		_, err = part.Write([]byte(name))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/avatars", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "more than one file for the key user")
+	assert.Equal(t, "\x89PNG\r\n\x1a\n", get("/avatars/42").Body.String())
+
+	// Saved to the directory by a hash of the key, so that keys with the same base name don't collide.
+	for _, key := range []string{"a/x", "b/x"} {
+		rec = upload("/docs", map[string]string{"path": key}, "file", "x.txt", key)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	files, err := os.ReadDir(filepath.Join(dir, "docs"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, ".txt", filepath.Ext(files[0].Name()))
+
+	rec = upload("/imports", nil, "csv", "users.csv", "id,name\n1,foo\n")
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	rec = get("/imports/users.csv")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "id,name\n1,foo\n", rec.Body.String())
+
+	rec = get("/imports")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"users.csv":{"name":"users.csv","contentType":"`)
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	patternRandom   = "random"
+	patternSequence = "sequence"
+
+	wordSize = 8
+)
+
+var (
+	errInvalidSize    = errors.New("invalid size")
+	errUnknownPattern = errors.New("unknown pattern")
+	errNegativeOffset = errors.New("negative offset")
+)
+
+// sizeUnits are multipliers of size suffixes, longer suffixes go first.
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3},
+	{"B", 1},
+}
+
+// parseSize parses size with an optional unit, e.g. "2GiB", "500MB" or "1024".
+func parseSize(raw string) (int64, error) {
+	value := strings.TrimSpace(raw)
+	multiplier := int64(1)
+
+	for _, unit := range sizeUnits {
+		if number, found := strings.CutSuffix(value, unit.suffix); found {
+			value, multiplier = strings.TrimSpace(number), unit.multiplier
+
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 || size > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("%w: [%s]", errInvalidSize, raw)
+	}
+
+	return size * multiplier, nil
+}
+
+// generator produces deterministic content of the given size, any part of it can be computed independently.
+type generator struct {
+	size     int64
+	seed     uint64
+	sequence bool
+	name     string
+}
+
+func newGenerator(cfg *config.Generate) (*generator, error) {
+	size, err := parseSize(cfg.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	gen := &generator{size: size, seed: uint64(cfg.Seed), name: cfg.Name} //nolint:gosec // bits of the seed are kept
+
+	switch cfg.Pattern {
+	case "", patternRandom:
+	case patternSequence:
+		gen.sequence = true
+	default:
+		return nil, fmt.Errorf("%w: [%s]", errUnknownPattern, cfg.Pattern)
+	}
+
+	return gen, nil
+}
+
+// etag identifies content of the generator.
+func (g *generator) etag() string {
+	pattern := patternRandom
+	if g.sequence {
+		pattern = patternSequence
+	}
+
+	return fmt.Sprintf(`"%s-%d-%d"`, pattern, g.seed, g.size)
+}
+
+// reader returns a new reader of the content.
+func (g *generator) reader() *generatedReader {
+	return &generatedReader{generator: g}
+}
+
+// word returns 8 bytes of content starting at the offset aligned to the word size.
+func (g *generator) word(index int64) [wordSize]byte {
+	var word [wordSize]byte
+
+	if g.sequence {
+		for idx := range word {
+			word[idx] = byte(index*wordSize + int64(idx)) //nolint:gosec // modulo 256 is intended
+		}
+
+		return word
+	}
+
+	binary.LittleEndian.PutUint64(word[:], splitmix64(g.seed+uint64(index))) //nolint:gosec // index is not negative
+
+	return word
+}
+
+// splitmix64 is a fast well distributed hash of the counter.
+func splitmix64(counter uint64) uint64 {
+	z := counter + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+
+	return z ^ (z >> 31)
+}
+
+// generatedReader reads content of a generator, it implements io.ReadSeeker for http.ServeContent.
+type generatedReader struct {
+	generator *generator
+	offset    int64
+}
+
+func (r *generatedReader) Read(data []byte) (int, error) {
+	if r.offset >= r.generator.size {
+		return 0, io.EOF
+	}
+
+	size := min(int64(len(data)), r.generator.size-r.offset)
+
+	for idx := int64(0); idx < size; {
+		offset := r.offset + idx
+		word := r.generator.word(offset / wordSize)
+		idx += int64(copy(data[idx:size], word[offset%wordSize:]))
+	}
+
+	r.offset += size
+
+	return int(size), nil
+}
+
+func (r *generatedReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.generator.size
+	}
+
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+
+	r.offset = offset
+
+	return offset, nil
+}
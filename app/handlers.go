@@ -1,17 +1,18 @@
 package app
 
 import (
-	"crypto/rand"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
-	"math/big"
 	"net/http"
-	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -24,10 +25,14 @@ var (
 	errTraverseNotFound  = errors.New("attribute not found in JSON traversal")
 	errTraverseNotString = errors.New("value is not a string in JSON traversal")
 	errTraverseNoParts   = errors.New("no parts in JSON path")
+
+	errPreconditionFailed = errors.New("precondition failed")
 )
 
 // GET /healthcheck.
-func healthcheckHandler(writer http.ResponseWriter, _ *http.Request) *appError {
+func healthcheckHandler(writer http.ResponseWriter, req *http.Request) *appError {
+	setAccessInfo(req, "/healthcheck", kindBuiltin)
+
 	return writeResponse(writer, map[string]any{
 		"status": "OK",
 	})
@@ -35,28 +40,41 @@ func healthcheckHandler(writer http.ResponseWriter, _ *http.Request) *appError {
 
 // GET /version.
 func versionHandler(version string) func(http.ResponseWriter, *http.Request) *appError {
-	return func(writer http.ResponseWriter, _ *http.Request) *appError {
+	return func(writer http.ResponseWriter, req *http.Request) *appError {
+		setAccessInfo(req, "/version", kindBuiltin)
+
 		return writeResponse(writer, map[string]any{
 			"version": version,
 		})
 	}
 }
 
-func apiHandler(log *slog.Logger, endpoint *config.Endpoint, status int,
-	jsonData []byte, proxy *Proxy, database *store) func(http.ResponseWriter, *http.Request) *appError {
-	errCnt, errCodes := setupFails(endpoint)
-
-	var ops uint64
-
-	return func(writer http.ResponseWriter, req *http.Request) *appError {
-		log.Debug("handling request", "uri", req.RequestURI)
+// routeOptions holds behaviours of an endpoint resolved against the global mock configuration.
+type routeOptions struct {
+	latency     *latency
+	throttle    *throttle
+	errors      *errorInjector
+	headers     *responseHeaders
+	uploads     string // directory to save uploaded files to
+	cache       *cachePolicy
+	variants    variants
+	compression *compression
+	transport   http.RoundTripper // transport of requests to the proxy upstream, the default one if nil
+	upstreams   *upstreamPool
+	chaos       *proxyChaos
+	auth        *authenticator
+}
 
-		if endpoint.Delay > 0 {
-			time.Sleep(time.Duration(endpoint.Delay) * time.Millisecond)
-		}
+func apiHandler(log *slog.Logger, endpoint *config.Endpoint, status int, body *responseBody,
+	proxy *Proxy, database *store, opts *routeOptions) func(http.ResponseWriter, *http.Request) *appError {
+	serve := func(writer http.ResponseWriter, req *http.Request) *appError {
+		// Proxy request to one of the upstreams, fall back to the mock response if all of them fail.
+		if opts.upstreams != nil {
+			if opts.upstreams.ServeHTTP(writer, req) {
+				return nil
+			}
 
-		if appErr := handleErrorSimulation(endpoint, &ops, errCnt, errCodes, log); appErr != nil {
-			return appErr
+			setAccessInfo(req, endpoint.Path, kindMock)
 		}
 
 		// Proxy request to the provided URL.
@@ -66,93 +84,211 @@ func apiHandler(log *slog.Logger, endpoint *config.Endpoint, status int,
 			return nil
 		}
 
-		writer.WriteHeader(status)
+		selected := body
+
+		if opts.variants != nil {
+			addVary(writer.Header(), "Accept")
+
+			selected = opts.variants.negotiate(req)
+			if selected == nil {
+				http.Error(writer, "none of the variants is acceptable", http.StatusNotAcceptable)
+
+				return nil
+			}
+		}
+
+		var data *templateData
+		if opts.headers != nil || selected.templated() {
+			data = newTemplateData(req)
+		}
+
+		opts.headers.apply(writer, data)
+
+		appErr := handleResponse(log, endpoint, status, selected, data, database, opts, writer, req)
+
+		opts.headers.applyTrailers(writer, data)
 
-		return handleResponse(log, endpoint, jsonData, database, writer, req)
+		return appErr
 	}
-}
 
-func handleErrorSimulation(endpoint *config.Endpoint, ops *uint64,
-	errCnt uint64, errCodes []int, log *slog.Logger) *appError {
-	if endpoint.Errors == nil {
-		return nil
+	return func(writer http.ResponseWriter, req *http.Request) *appError {
+		log.Debug("handling request", "uri", req.RequestURI)
+
+		opts.latency.wait(req.Context())
+
+		if outcome := opts.errors.next(time.Now()); outcome != nil {
+			setAccessInfo(req, endpoint.Path, kindInjected)
+
+			if outcome.fault != "" {
+				return injectFault(log, outcome.fault, endpoint.Errors.TruncateAfter, writer, req, serve)
+			}
+
+			writeInjectedError(log, endpoint.Errors, outcome, writer)
+
+			return nil
+		}
+
+		if endpoint.Proxy != "" || opts.upstreams != nil {
+			setAccessInfo(req, endpoint.Path, kindProxy)
+		} else {
+			setAccessInfo(req, endpoint.Path, kindMock)
+		}
+
+		return serve(writer, req)
 	}
+}
 
-	newOps := atomic.AddUint64(ops, 1)
-	if newOps != errCnt {
-		return nil
+func handleResponse(log *slog.Logger, endpoint *config.Endpoint, status int, body *responseBody,
+	data *templateData, database *store, opts *routeOptions, writer http.ResponseWriter, req *http.Request) *appError {
+	// Serve static body (json, jsonPath, body, bodyBase64, bodyFile, generate or soapFault) if set.
+	if body != nil {
+		return writeBody(log, status, body, data, opts, writer, req)
 	}
 
-	atomic.StoreUint64(ops, 0)
+	// Dynamic read/write operation.
+	if endpoint.Dynamic != nil {
+		return handleDynamic(log, endpoint, status, database, opts, writer, req)
+	}
 
-	idx, _ := rand.Int(rand.Reader, big.NewInt(int64(len(errCodes))))
-	code := errCodes[idx.Int64()]
+	writer.WriteHeader(status)
 
-	return &appError{
-		Code:    code,
-		Message: "failed with predefined error",
-		Log:     log,
-	}
+	return nil
 }
 
-func handleResponse(log *slog.Logger, endpoint *config.Endpoint, jsonData []byte,
-	database *store, writer http.ResponseWriter, req *http.Request) *appError {
-	// Serve static JSON file from JSONPath if set.
-	if endpoint.JSONPath != "" {
-		_, err := writer.Write(jsonData)
-		if err != nil {
-			return &appError{
-				Error:   err,
-				Message: "error in writing data from JSON path to client",
-				Log:     log,
-			}
+func writeBody(log *slog.Logger, status int, body *responseBody, data *templateData, opts *routeOptions,
+	writer http.ResponseWriter, req *http.Request) *appError {
+	setContentType(writer, body.contentType)
+
+	var content io.ReadSeeker
+
+	if body.generated != nil {
+		if body.generated.name != "" {
+			writer.Header().Set("Content-Disposition", contentDisposition(body.generated.name))
+		}
+
+		// Synthetic bodies are too large to hash, they are identified by their parameters.
+		if opts.cache.validate(writer, req, status, body.generated.etag()) {
+			writer.WriteHeader(http.StatusNotModified)
+
+			return nil
+		}
+
+		content = body.generated.reader()
+	} else {
+		rendered := body.render(data)
+
+		if opts.cache.validate(writer, req, status, entityTag(rendered)) {
+			writer.WriteHeader(http.StatusNotModified)
+
+			return nil
 		}
 
+		content = bytes.NewReader(rendered)
+	}
+
+	// File-backed and synthetic bodies support byte ranges, unless trailers need a chunked response.
+	if status == http.StatusOK && body.seekable() && !announcesTrailers(writer) {
+		http.ServeContent(writer, req, "", body.modTime, content)
+
 		return nil
 	}
 
-	// Serve JSON from API configuration instead.
-	if endpoint.JSON != nil {
-		log.Debug("returning JSON object", "object", fmt.Sprintf("%#v", endpoint.JSON))
+	if !announcesTrailers(writer) {
+		size, _ := content.Seek(0, io.SeekEnd)
+		_, _ = content.Seek(0, io.SeekStart)
 
-		return writeResponse(writer, endpoint.JSON)
+		writer.Header().Set("Content-Length", strconv.FormatInt(size, 10))
 	}
 
-	// Dynamic read/write operation.
-	if endpoint.Dynamic != nil {
-		return handleDynamic(log, endpoint, database, writer, req)
+	writer.WriteHeader(status)
+
+	_, err := io.Copy(writer, content)
+	if err != nil {
+		return &appError{
+			Error:   err,
+			Message: "error in writing response body to client",
+			Log:     log,
+		}
 	}
 
 	return nil
 }
 
-func handleDynamic(log *slog.Logger, endpoint *config.Endpoint,
-	database *store, writer http.ResponseWriter, req *http.Request) *appError {
-	if endpoint.Dynamic.Write != nil {
-		return handleDynamicWrite(log, endpoint, database, req)
+// setContentType sets Content-Type unless it is already set, e.g. by the "headers" of an endpoint.
+func setContentType(writer http.ResponseWriter, contentType string) {
+	if writer.Header().Get("Content-Type") == "" {
+		writer.Header().Set("Content-Type", contentType)
 	}
+}
 
-	if endpoint.Dynamic.Read != nil {
-		return handleDynamicRead(log, endpoint, database, writer, req)
+func handleDynamic(log *slog.Logger, endpoint *config.Endpoint, status int,
+	database *store, opts *routeOptions, writer http.ResponseWriter, req *http.Request) *appError {
+	switch {
+	case endpoint.Dynamic.Write != nil:
+		appErr := handleDynamicWrite(log, endpoint, database, opts, writer, req)
+		if appErr != nil {
+			return appErr
+		}
+	case endpoint.Dynamic.Read != nil && endpoint.Dynamic.Read.File != nil:
+		return handleDynamicReadFile(log, endpoint, status, database, opts, writer, req)
+	case endpoint.Dynamic.Read != nil && endpoint.Dynamic.Read.JSON != nil:
+		return handleDynamicRead(log, endpoint, status, database, opts, writer, req)
 	}
 
+	writer.WriteHeader(status)
+
 	return nil
 }
 
 func handleDynamicWrite(log *slog.Logger, endpoint *config.Endpoint,
-	database *store, req *http.Request) *appError {
-	input := map[string]any{}
-
-	appErr := readRequestJSON(req.Context(), req, &input)
+	database *store, opts *routeOptions, writer http.ResponseWriter, req *http.Request) *appError {
+	input, form, appErr := readRequestInput(req)
 	if appErr != nil {
+		appErr.Log = log
+
 		return appErr
 	}
+	defer form.close()
+
+	if endpoint.Dynamic.Write.JSON != nil {
+		appErr = writeDynamicValue(log, endpoint, database, input, writer, req)
+		if appErr != nil {
+			return appErr
+		}
+	}
+
+	if endpoint.Dynamic.Write.Files != nil {
+		dir := opts.uploads
+		if namespace := dynamicNamespace(endpoint, req); namespace != "" && dir != "" {
+			dir = namespaceDir(dir, namespace)
+		}
 
+		name := entityName(endpoint, endpoint.Dynamic.Write.Files.Name, req)
+
+		keys, err := saveUploads(endpoint, name, dir, form, input, database)
+		if err != nil {
+			return &appError{
+				Error:   err,
+				Message: fmt.Sprintf("error in storing uploaded files: %v", err),
+				Code:    http.StatusBadRequest,
+				Log:     log,
+			}
+		}
+
+		log.Debug("storing uploaded files", "name", endpoint.Dynamic.Write.Files.Name, "keys", keys)
+	}
+
+	return nil
+}
+
+func writeDynamicValue(log *slog.Logger, endpoint *config.Endpoint, database *store, input map[string]any,
+	writer http.ResponseWriter, req *http.Request) *appError {
 	key, err := findKeyInJSON(endpoint.Dynamic.Write.JSON.Key, input)
 	if err != nil {
 		return &appError{
 			Error:   err,
 			Message: "error in finding the key",
+			Code:    http.StatusBadRequest,
 			Log:     log,
 		}
 	}
@@ -162,78 +298,211 @@ func handleDynamicWrite(log *slog.Logger, endpoint *config.Endpoint,
 		return &appError{
 			Error:   err,
 			Message: "error in finding the value",
+			Code:    http.StatusBadRequest,
 			Log:     log,
 		}
 	}
 
 	log.Debug("writing dynamic entry", "name", endpoint.Dynamic.Write.JSON.Name, "key", key)
-	database.Write(endpoint.Dynamic.Write.JSON.Name, key, value)
+
+	// Preconditions make writes conditional on the current value, e.g. for optimistic concurrency.
+	name := entityName(endpoint, endpoint.Dynamic.Write.JSON.Name, req)
+
+	err = database.Update(name, key, func(current any, found bool) (any, error) {
+		var currentTag string
+		if found {
+			currentTag = valueTag(current)
+		}
+
+		if !writePreconditionsMet(req, currentTag, found) {
+			return nil, fmt.Errorf("%w: key [%s]", errPreconditionFailed, key)
+		}
+
+		return value, nil
+	})
+	if err != nil {
+		return &appError{
+			Error:   err,
+			Message: fmt.Sprintf("precondition failed for key [%s]", key),
+			Code:    http.StatusPreconditionFailed,
+			Log:     log,
+		}
+	}
+
+	writer.Header().Set("ETag", valueTag(value))
 
 	return nil
 }
 
-func handleDynamicRead(log *slog.Logger, endpoint *config.Endpoint,
-	database *store, writer http.ResponseWriter, req *http.Request) *appError {
+func handleDynamicRead(log *slog.Logger, endpoint *config.Endpoint, status int,
+	database *store, opts *routeOptions, writer http.ResponseWriter, req *http.Request) *appError {
 	var (
 		key   string
 		value any
 		found bool
 	)
 
+	name := entityName(endpoint, endpoint.Dynamic.Read.JSON.Name, req)
+
 	if endpoint.Dynamic.Read.JSON.KeyParam == "" {
-		value, found = database.ReadAll(endpoint.Dynamic.Read.JSON.Name)
+		value, found = database.ReadAll(name)
 	} else {
 		key = chi.URLParam(req, endpoint.Dynamic.Read.JSON.KeyParam)
-		value, found = database.Read(endpoint.Dynamic.Read.JSON.Name, key)
+		value, found = database.Read(name, key)
 	}
 
 	if !found {
 		return &appError{
 			Message: fmt.Sprintf("value not found for key [%s]", key),
+			Code:    http.StatusNotFound,
 			Log:     log,
 		}
 	}
 
 	log.Debug("reading dynamic entry", "name", endpoint.Dynamic.Read.JSON.Name, "key", key)
 
-	return writeResponse(writer, value)
+	content, err := encodeJSON(value)
+	if err != nil {
+		return &appError{
+			Error:   err,
+			Message: fmt.Sprintf("error in response write: %v", err),
+			Code:    http.StatusInternalServerError,
+			Log:     log,
+		}
+	}
+
+	setContentType(writer, contentTypeJSON)
+
+	if opts.cache.validate(writer, req, status, entityTag(content)) {
+		writer.WriteHeader(http.StatusNotModified)
+
+		return nil
+	}
+
+	writer.WriteHeader(status)
+
+	_, err = writer.Write(content)
+	if err != nil {
+		return &appError{
+			Error:   err,
+			Message: "error in writing response body to client",
+			Log:     log,
+		}
+	}
+
+	return nil
 }
 
-func setupFails(endpoint *config.Endpoint) (uint64, []int) {
-	if endpoint.Errors == nil {
-		return 0, nil
+func handleDynamicReadFile(log *slog.Logger, endpoint *config.Endpoint, status int,
+	database *store, opts *routeOptions, writer http.ResponseWriter, req *http.Request) *appError {
+	key := chi.URLParam(req, endpoint.Dynamic.Read.File.KeyParam)
+
+	value, _ := database.Read(entityName(endpoint, endpoint.Dynamic.Read.File.Name, req), key)
+
+	file, isFile := value.(*storedFile)
+	if !isFile {
+		return &appError{
+			Message: fmt.Sprintf("file not found for key [%s]", key),
+			Code:    http.StatusNotFound,
+			Log:     log,
+		}
 	}
 
-	var errCodes []int
+	data, err := file.content()
+	if err != nil {
+		return &appError{
+			Error:   err,
+			Message: "error in reading uploaded file",
+			Code:    http.StatusInternalServerError,
+			Log:     log,
+		}
+	}
 
-	if len(endpoint.Errors.Statuses) > 0 {
-		errCodes = endpoint.Errors.Statuses
-	} else {
-		errCodes = []int{http.StatusInternalServerError}
+	log.Debug("reading uploaded file", "name", endpoint.Dynamic.Read.File.Name, "key", key)
+
+	setContentType(writer, file.ContentType)
+	writer.Header().Set("Content-Disposition", contentDisposition(file.Name))
+
+	if opts.cache.validate(writer, req, status, entityTag(data)) {
+		writer.WriteHeader(http.StatusNotModified)
+
+		return nil
 	}
 
-	errCnt := uint64(1.0 / endpoint.Errors.Sample)
+	if status == http.StatusOK && !announcesTrailers(writer) {
+		http.ServeContent(writer, req, "", file.Modified, bytes.NewReader(data))
 
-	slog.Debug("every Nth request will fail",
-		"endpoint", endpoint.Path,
-		"every_nth_err", errCnt,
-		"errCodes", fmt.Sprintf("%v", errCodes))
+		return nil
+	}
+
+	if !announcesTrailers(writer) {
+		writer.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	}
 
-	return errCnt, errCodes
+	writer.WriteHeader(status)
+
+	_, err = writer.Write(data)
+	if err != nil {
+		return &appError{
+			Error:   err,
+			Message: "error in writing response body to client",
+			Log:     log,
+		}
+	}
+
+	return nil
 }
 
-func setupAPI(cfg *config.Config, mockPath string, mck *config.Mock, router *chi.Mux) {
+// dynamicNamespace returns the namespace of dynamic entities of the request, empty if they are shared.
+func dynamicNamespace(endpoint *config.Endpoint, req *http.Request) string {
+	if !endpoint.Dynamic.PerPrincipal {
+		return ""
+	}
+
+	return principalOf(req)
+}
+
+// namespaceDir returns the directory of uploads of the namespace. Principals come from clients, e.g. "sub"
+// of a minted token can be "..", so the directory is named by a hash of the principal instead.
+func namespaceDir(dir, namespace string) string {
+	digest := sha256.Sum256([]byte(namespace))
+
+	return filepath.Join(dir, hex.EncodeToString(digest[:]))
+}
+
+// entityName returns the name of the dynamic entity in the store, entities of principals are kept apart
+// if the endpoint asks for it.
+func entityName(endpoint *config.Endpoint, name string, req *http.Request) string {
+	if namespace := dynamicNamespace(endpoint, req); namespace != "" {
+		return name + "@" + namespace
+	}
+
+	return name
+}
+
+func setupAPI(cfg *config.Config, mockPath string, mck *config.Mock, iss *issuer, router *chi.Mux) {
 	database := newStore()
+	groups := map[string]*routeGroup{}
+
+	var routes []string
 
-	for _, endpoint := range mck.Endpoints {
+	for idx, endpoint := range mck.Endpoints {
 		status := endpoint.Status
 		if status <= 0 {
 			status = http.StatusOK
+			if endpoint.SOAPFault != nil {
+				status = http.StatusInternalServerError
+			}
+		}
+
+		methods := endpoint.Methods
+		if len(methods) == 0 {
+			methods = []string{http.MethodGet}
 		}
 
 		logger := slog.Default().With(
 			"endpoint", endpoint.Path,
-			"methods", fmt.Sprintf("%v", endpoint.Methods),
+			"methods", fmt.Sprintf("%v", methods),
 		)
 
 		route := resolveRoute(endpoint.Path)
@@ -241,7 +510,122 @@ func setupAPI(cfg *config.Config, mockPath string, mck *config.Mock, router *chi
 		logger = logger.With("route", route)
 		logger.Info("setting up endpoint")
 
-		configureRoute(router, route, cfg, mockPath, endpoint, logger, status, database)
+		headers, err := newResponseHeaders(endpoint)
+		if err != nil {
+			logger.Error(fmt.Sprintf("error in setting up response headers for path [%s]: %v", endpoint.Path, err))
+
+			continue
+		}
+
+		match, err := newMatcher(endpoint.Match)
+		if err != nil {
+			logger.Error(fmt.Sprintf("error in setting up request matching for path [%s]: %v", endpoint.Path, err))
+
+			continue
+		}
+
+		comp, err := newCompression(endpoint.Compression, mck.Compression)
+		if err != nil {
+			logger.Error(fmt.Sprintf("error in setting up compression for path [%s]: %v", endpoint.Path, err))
+
+			continue
+		}
+
+		var transport http.RoundTripper
+
+		if endpoint.Proxy != "" || endpoint.Upstreams != nil {
+			transport, err = newProxyTransport(mockPath, endpoint.ProxyOptions, mck.ProxyOptions)
+			if err != nil {
+				logger.Error(fmt.Sprintf("error in setting up proxy options for path [%s]: %v", endpoint.Path, err))
+
+				continue
+			}
+		}
+
+		rnd := newRandom(mck.Seed, uint64(idx))
+
+		opts := &routeOptions{
+			throttle:    newThrottle(endpoint.Throttle, mck.Throttle),
+			headers:     headers,
+			compression: comp,
+			transport:   transport,
+		}
+
+		opts.latency, err = newLatency(endpoint, mck.Latency, rnd)
+		if err != nil {
+			logger.Error(fmt.Sprintf("error in setting up latency for path [%s]: %v", endpoint.Path, err))
+
+			continue
+		}
+
+		opts.errors, err = newErrorInjector(endpoint.Errors, rnd, logger)
+		if err != nil {
+			logger.Error(fmt.Sprintf("error in setting up errors for path [%s]: %v", endpoint.Path, err))
+
+			continue
+		}
+
+		opts.auth, err = newAuthenticator(endpoint, mck.Auth, iss, logger)
+		if err != nil {
+			logger.Error(fmt.Sprintf("error in setting up auth for path [%s]: %v", endpoint.Path, err))
+
+			continue
+		}
+
+		if endpoint.Proxy != "" || endpoint.Upstreams != nil {
+			opts.chaos, err = newProxyChaos(endpoint.ProxyChaos, rnd, logger)
+			if err != nil {
+				logger.Error(fmt.Sprintf("error in setting up proxy chaos for path [%s]: %v", endpoint.Path, err))
+
+				continue
+			}
+		}
+
+		if endpoint.Upstreams != nil {
+			opts.upstreams, err = newUpstreamPool(cfg.Server.Addr, endpoint, transport, opts.chaos, rnd, logger)
+			if err != nil {
+				logger.Error(fmt.Sprintf("error in setting up upstreams for path [%s]: %v", endpoint.Path, err))
+
+				continue
+			}
+		}
+
+		if endpoint.Dynamic != nil && endpoint.Dynamic.Write != nil && endpoint.Dynamic.Write.Files != nil &&
+			endpoint.Dynamic.Write.Files.Dir != "" {
+			opts.uploads = filepath.Join(mockPath, endpoint.Dynamic.Write.Files.Dir)
+		}
+
+		handler, err := endpointHandler(cfg, mockPath, endpoint, logger, status, database, opts)
+		if err != nil {
+			logger.Error(err.Error())
+
+			continue
+		}
+
+		if endpoint.Static != "" {
+			methods = nil
+		}
+
+		group, exists := groups[route]
+		if !exists {
+			group = &routeGroup{router: router}
+			groups[route] = group
+			routes = append(routes, route)
+		}
+
+		group.add(&routeEndpoint{
+			methods: methods,
+			matcher: match,
+			cors:    len(endpoint.AllowCors) > 0,
+			handler: handler,
+		})
+	}
+
+	// Endpoints sharing a route are mounted together and tried in order of their definition.
+	for _, route := range routes {
+		router.Route(route, func(subrouter chi.Router) {
+			subrouter.Handle("/*", groups[route])
+		})
 	}
 }
 
@@ -266,60 +650,65 @@ func resolveRoute(endpointPath string) string {
 	}
 }
 
-func configureRoute(router *chi.Mux, route string, cfg *config.Config, mockPath string,
-	endpoint *config.Endpoint, logger *slog.Logger, status int, database *store) {
-	router.Route(route, func(subrouter chi.Router) {
-		if len(endpoint.AllowCors) > 0 {
-			subrouter.Use(NewCORS(endpoint.AllowCors...).Middleware)
-		}
+func endpointHandler(cfg *config.Config, mockPath string, endpoint *config.Endpoint,
+	logger *slog.Logger, status int, database *store, opts *routeOptions) (http.Handler, error) {
+	var handler http.Handler
 
-		var err error
+	if endpoint.Static != "" {
+		root := http.Dir(endpoint.Static)
+		fileServer := precompressed(root, http.FileServer(root))
 
-		var jsonData []byte
+		handler = http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			setAccessInfo(req, endpoint.Path, kindStatic)
+			fileServer.ServeHTTP(writer, req)
+		})
+	} else {
+		body, err := loadBody(mockPath, endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("error in loading response body for path [%s]: %w", endpoint.Path, err)
+		}
 
-		if endpoint.JSONPath != "" {
-			jsonData, err = readJSON(mockPath, endpoint.JSONPath)
-			if err != nil {
-				logger.Error(fmt.Sprintf("error in reading JSON from the path [%s] for path [%s]",
-					endpoint.JSONPath, endpoint.Path))
+		opts.cache, err = newCachePolicy(endpoint.Cache, body)
+		if err != nil {
+			return nil, fmt.Errorf("error in setting up caching for path [%s]: %w", endpoint.Path, err)
+		}
 
-				return
-			}
+		opts.variants, err = loadVariants(mockPath, endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("error in loading variants for path [%s]: %w", endpoint.Path, err)
 		}
 
 		var proxy *Proxy
 
 		if endpoint.Proxy != "" {
-			proxy, err = newProxy(cfg.Server.Addr, endpoint.Proxy, logger)
+			proxy, err = newProxy(cfg.Server.Addr, endpoint.Proxy, endpoint.ProxyRules, opts.transport, logger)
 			if err != nil {
-				logger.Error(fmt.Sprintf("error in creating a proxy for path [%s]: %v",
-					endpoint.Path, err))
-
-				return
+				return nil, fmt.Errorf("error in creating a proxy for path [%s]: %w", endpoint.Path, err)
 			}
+
+			proxy.chaos = opts.chaos
 		}
 
-		if endpoint.Static != "" {
-			subrouter.HandleFunc("/*", http.FileServer(http.Dir(endpoint.Static)).ServeHTTP)
+		handler = appHandler(apiHandler(logger, endpoint, status, body, proxy, database, opts))
+	}
 
-			return
-		}
+	if opts.compression != nil {
+		handler = opts.compression.Middleware(handler)
+	}
 
-		for _, method := range endpoint.Methods {
-			subrouter.Method(method, "/*", appHandler(apiHandler(logger, endpoint, status, jsonData, proxy, database)))
-		}
-	})
-}
+	if opts.throttle != nil {
+		handler = opts.throttle.Middleware(handler)
+	}
 
-func readJSON(mockPath, jsonPath string) ([]byte, error) {
-	filePath := filepath.Join(mockPath, jsonPath)
+	// Credentials are checked before anything else, but after CORS, so that preflight requests pass
+	// and rejections carry CORS headers.
+	handler = opts.auth.Middleware(handler)
 
-	data, err := os.ReadFile(filepath.Clean(filePath))
-	if err != nil {
-		return nil, fmt.Errorf("reading JSON file %s: %w", filePath, err)
+	if len(endpoint.AllowCors) > 0 {
+		handler = NewCORS(endpoint.AllowCors...).endpointMiddleware(endpoint.Path, handler)
 	}
 
-	return data, nil
+	return handler, nil
 }
 
 func findKeyInJSON(jsonPath string, obj map[string]any) (string, error) {
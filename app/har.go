@@ -0,0 +1,298 @@
+package app
+
+import (
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	harVersion     = "1.2"
+	harHTTPVersion = "HTTP/1.1"
+	harUnknownSize = -1
+)
+
+// harFile is an HTTP Archive, see http://www.softwareishard.com/blog/har-12-spec/.
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string      `json:"version"`
+	Creator harCreator  `json:"creator"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // milliseconds
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string         `json:"mimeType"`
+	Text     string         `json:"text"`
+	Params   []harNameValue `json:"params,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"` // "base64" for binary content
+
+	Compression int `json:"compression,omitempty"` // bytes saved by Content-Encoding
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harHeader converts HAR headers to http.Header, skipping HTTP/2 pseudo-headers.
+func harHeader(values []harNameValue) http.Header {
+	header := http.Header{}
+
+	for _, value := range values {
+		if strings.HasPrefix(value.Name, ":") {
+			continue
+		}
+
+		header.Add(value.Name, value.Value)
+	}
+
+	return header
+}
+
+// journalEntry converts the HAR entry to a journal entry, content of HAR is decoded,
+// so that encoding headers of the response are dropped.
+func (e *harEntry) journalEntry() (*JournalEntry, error) {
+	reqURL, err := url.Parse(e.Request.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing request URL: %w", err)
+	}
+
+	entry := &JournalEntry{
+		Time:     e.StartedDateTime,
+		Duration: time.Duration(e.Time * float64(time.Millisecond)),
+		Request: RecordedRequest{
+			Method: e.Request.Method,
+			Host:   reqURL.Host,
+			URL:    reqURL.RequestURI(),
+			Path:   reqURL.Path,
+			Query:  reqURL.Query(),
+			Header: harHeader(e.Request.Headers),
+		},
+		Response: RecordedResponse{
+			Status: e.Response.Status,
+			Header: harHeader(e.Response.Headers),
+		},
+	}
+
+	if e.Request.PostData != nil {
+		entry.Request.Body = e.Request.PostData.Text
+	}
+
+	body := []byte(e.Response.Content.Text)
+
+	if e.Response.Content.Encoding == bodyEncodingBase64 {
+		body, err = base64.StdEncoding.DecodeString(e.Response.Content.Text)
+		if err != nil {
+			return nil, fmt.Errorf("decoding response content: %w", err)
+		}
+	}
+
+	entry.Response.Body, entry.Response.BodyEncoding = recordBody(body)
+
+	for _, name := range []string{"Content-Encoding", "Content-Length", "Transfer-Encoding"} {
+		entry.Response.Header.Del(name)
+	}
+
+	if entry.Response.Header.Get("Content-Type") == "" && e.Response.Content.MimeType != "" {
+		entry.Response.Header.Set("Content-Type", e.Response.Content.MimeType)
+	}
+
+	return entry, nil
+}
+
+// newHARFile converts journal entries to an HTTP Archive.
+func newHARFile(entries []*JournalEntry) *harFile {
+	har := &harFile{Log: harLog{
+		Version: harVersion,
+		Creator: harCreator{Name: "gomock", Version: buildVersion()},
+		Entries: make([]*harEntry, 0, len(entries)),
+	}}
+
+	for _, entry := range entries {
+		har.Log.Entries = append(har.Log.Entries, harEntryOf(entry))
+	}
+
+	return har
+}
+
+// harEntryOf converts the journal entry to a HAR entry, the whole duration is reported as waiting.
+func harEntryOf(entry *JournalEntry) *harEntry {
+	host := entry.Request.Host
+	if host == "" {
+		host = "localhost"
+	}
+
+	reqBody := recordedBody(entry.Request.Body, entry.Request.BodyEncoding)
+	respBody := recordedBody(entry.Response.Body, entry.Response.BodyEncoding)
+	content := harContentOf(entry.Response.Header, respBody)
+	wait := float64(entry.Duration) / float64(time.Millisecond)
+
+	har := &harEntry{
+		StartedDateTime: entry.Time,
+		Time:            wait,
+		Request: harRequest{
+			Method:      entry.Request.Method,
+			URL:         "http://" + host + entry.Request.URL,
+			HTTPVersion: harHTTPVersion,
+			Cookies:     harCookies(entry.Request.Header),
+			Headers:     harNameValues(entry.Request.Header),
+			QueryString: harNameValues(entry.Request.Query),
+			HeadersSize: harUnknownSize,
+			BodySize:    len(reqBody),
+		},
+		Response: harResponse{
+			Status:      entry.Response.Status,
+			StatusText:  http.StatusText(entry.Response.Status),
+			HTTPVersion: harHTTPVersion,
+			Cookies:     harSetCookies(entry.Response.Header),
+			Headers:     harNameValues(entry.Response.Header),
+			Content:     content,
+			RedirectURL: entry.Response.Header.Get("Location"),
+			HeadersSize: harUnknownSize,
+			BodySize:    len(respBody),
+		},
+		Timings: harTimings{Wait: wait},
+	}
+
+	if len(reqBody) > 0 {
+		har.Request.PostData = &harPostData{
+			MimeType: entry.Request.Header.Get("Content-Type"),
+			Text:     string(reqBody),
+		}
+	}
+
+	return har
+}
+
+// harContentOf returns the content of the response, decoded if the body is compressed
+// and kept as it is if it fails to decode, e.g. because it was cut by the journal.
+func harContentOf(header http.Header, body []byte) harContent {
+	content := harContent{MimeType: header.Get("Content-Type")}
+
+	if encoding := header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		decoded, err := decompress(encoding, body)
+		if err == nil {
+			content.Compression = len(decoded) - len(body)
+			body = decoded
+		}
+	}
+
+	content.Size = len(body)
+	content.Text, content.Encoding = recordBody(body)
+
+	return content
+}
+
+// harNameValues converts headers or query parameters to HAR name-value pairs sorted by name.
+func harNameValues[T ~map[string][]string](values T) []harNameValue {
+	pairs := make([]harNameValue, 0, len(values))
+
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		for _, value := range values[name] {
+			pairs = append(pairs, harNameValue{Name: name, Value: value})
+		}
+	}
+
+	return pairs
+}
+
+func harCookies(header http.Header) []harNameValue {
+	pairs := []harNameValue{}
+
+	for _, line := range header.Values("Cookie") {
+		cookies, err := http.ParseCookie(line)
+		if err != nil {
+			continue
+		}
+
+		for _, cookie := range cookies {
+			pairs = append(pairs, harNameValue{Name: cookie.Name, Value: cookie.Value})
+		}
+	}
+
+	return pairs
+}
+
+func harSetCookies(header http.Header) []harNameValue {
+	pairs := []harNameValue{}
+
+	for _, line := range header.Values("Set-Cookie") {
+		cookie, err := http.ParseSetCookie(line)
+		if err != nil {
+			continue
+		}
+
+		pairs = append(pairs, harNameValue{Name: cookie.Name, Value: cookie.Value})
+	}
+
+	return pairs
+}
+
+// buildVersion returns the module version of the running binary, if it is known.
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	return info.Main.Version
+}
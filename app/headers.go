@@ -0,0 +1,158 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+// responseHeaders sets configured headers, cookies and trailers of an endpoint.
+type responseHeaders struct {
+	headers  map[string]*valueTemplate
+	cookies  []*cookieTemplate
+	trailers map[string]*valueTemplate
+}
+
+type cookieTemplate struct {
+	cookie  http.Cookie
+	value   *valueTemplate
+	expires time.Time
+}
+
+// newResponseHeaders compiles headers of the endpoint, returns nil if none are configured.
+func newResponseHeaders(endpoint *config.Endpoint) (*responseHeaders, error) {
+	if len(endpoint.Headers) == 0 && len(endpoint.Cookies) == 0 && len(endpoint.Trailers) == 0 {
+		return nil, nil
+	}
+
+	headers, err := compileValues("header", endpoint.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	trailers, err := compileValues("trailer", endpoint.Trailers)
+	if err != nil {
+		return nil, err
+	}
+
+	cookies := make([]*cookieTemplate, 0, len(endpoint.Cookies))
+
+	for _, cfg := range endpoint.Cookies {
+		cookie, err := newCookieTemplate(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		cookies = append(cookies, cookie)
+	}
+
+	return &responseHeaders{
+		headers:  headers,
+		cookies:  cookies,
+		trailers: trailers,
+	}, nil
+}
+
+func compileValues(kind string, values map[string]string) (map[string]*valueTemplate, error) {
+	compiled := make(map[string]*valueTemplate, len(values))
+
+	for name, raw := range values {
+		value, err := newValueTemplate(kind+" "+name, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		compiled[http.CanonicalHeaderKey(name)] = value
+	}
+
+	return compiled, nil
+}
+
+func newCookieTemplate(cfg *config.Cookie) (*cookieTemplate, error) {
+	value, err := newValueTemplate("cookie "+cfg.Name, cfg.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	cookie := &cookieTemplate{
+		cookie: http.Cookie{
+			Name:     cfg.Name,
+			Path:     cfg.Path,
+			Domain:   cfg.Domain,
+			MaxAge:   cfg.MaxAge,
+			Secure:   cfg.Secure,
+			HttpOnly: cfg.HTTPOnly,
+		},
+		value: value,
+	}
+
+	if cfg.Expires != "" {
+		cookie.expires, err = http.ParseTime(cfg.Expires)
+		if err != nil {
+			return nil, fmt.Errorf("parsing expires of cookie [%s]: %w", cfg.Name, err)
+		}
+	}
+
+	switch strings.ToLower(cfg.SameSite) {
+	case "lax":
+		cookie.cookie.SameSite = http.SameSiteLaxMode
+	case "strict":
+		cookie.cookie.SameSite = http.SameSiteStrictMode
+	case "none":
+		cookie.cookie.SameSite = http.SameSiteNoneMode
+	}
+
+	return cookie, nil
+}
+
+// apply sets headers and cookies and announces trailers, it must be called before the status is written.
+func (h *responseHeaders) apply(writer http.ResponseWriter, data *templateData) {
+	if h == nil {
+		return
+	}
+
+	header := writer.Header()
+
+	for name, value := range h.headers {
+		header.Set(name, value.render(data))
+	}
+
+	for _, tmpl := range h.cookies {
+		cookie := tmpl.cookie
+		cookie.Value = tmpl.value.render(data)
+		cookie.Expires = tmpl.expires
+		http.SetCookie(writer, &cookie)
+	}
+
+	names := make([]string, 0, len(h.trailers))
+	for name := range h.trailers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		header.Add("Trailer", name)
+	}
+}
+
+// announcesTrailers reports if the response announces trailers, such a response must not set Content-Length,
+// otherwise it isn't chunked and trailers are never sent.
+func announcesTrailers(writer http.ResponseWriter) bool {
+	return writer.Header().Get("Trailer") != ""
+}
+
+// applyTrailers sets values of announced trailers, it must be called after the body is written.
+func (h *responseHeaders) applyTrailers(writer http.ResponseWriter, data *templateData) {
+	if h == nil {
+		return
+	}
+
+	for name, value := range h.trailers {
+		writer.Header().Set(name, value.render(data))
+	}
+}
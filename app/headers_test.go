@@ -0,0 +1,120 @@
+package app_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock/app"
+	"github.com/smeshkov/gomock/config"
+)
+
+func TestHeaders_CookiesAndTrailers(t *testing.T) {
+	t.Parallel()
+
+	handler := app.RegisterHandlers("test", ".", &config.Config{}, &config.Mock{
+		Endpoints: []*config.Endpoint{
+			{
+				Methods: []string{http.MethodPost},
+				Path:    "/users/{id}",
+				Status:  http.StatusCreated,
+				JSON:    map[string]any{"ok": true},
+				Headers: map[string]string{
+					"Location": "/users/{{.Params.id}}",
+					"ETag":     `"v1"`,
+					"Link":     `</users?page={{add (default 1 .Query.page) 1}}>; rel="next"`,
+				},
+				Cookies: []*config.Cookie{
+					{Name: "session", Value: `{{index .Header "X-Session"}}`, HTTPOnly: true, SameSite: "strict"},
+				},
+				Trailers: map[string]string{"X-Checksum": "abc"},
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/42?page=2", nil)
+	req.Header.Set("X-Session", "s3cr3t")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "/users/42", resp.Header.Get("Location"))
+	assert.Equal(t, `"v1"`, resp.Header.Get("ETag"))
+	assert.Equal(t, `</users?page=3>; rel="next"`, resp.Header.Get("Link"))
+	assert.Equal(t, "session=s3cr3t; HttpOnly; SameSite=Strict", resp.Header.Get("Set-Cookie"))
+	assert.Equal(t, "abc", resp.Trailer.Get("X-Checksum"))
+}
+
+func TestHeaders_TrailersOverConnection(t *testing.T) {
+	t.Parallel()
+
+	for _, status := range []int{http.StatusOK, http.StatusCreated} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(app.RegisterHandlers("test", ".", &config.Config{}, &config.Mock{
+				Endpoints: []*config.Endpoint{
+					{
+						Methods:  []string{http.MethodGet},
+						Path:     "/users",
+						Status:   status,
+						JSON:     []any{map[string]any{"id": 1}},
+						Trailers: map[string]string{"X-Checksum": "abc"},
+					},
+				},
+			}))
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL + "/users") //nolint:noctx // test request
+			require.NoError(t, err)
+
+			defer func() { _ = resp.Body.Close() }()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, status, resp.StatusCode)
+			assert.JSONEq(t, `[{"id":1}]`, string(body))
+			assert.Equal(t, int64(-1), resp.ContentLength)
+			assert.Equal(t, "abc", resp.Trailer.Get("X-Checksum"))
+		})
+	}
+}
+
+func TestHeaders_JSONPathContentType(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users.json"), []byte(`[]`), 0o600))
+
+	handler := app.RegisterHandlers("test", dir, &config.Config{}, &config.Mock{
+		Endpoints: []*config.Endpoint{
+			{Methods: []string{http.MethodGet}, Path: "/users", JSONPath: "users.json"},
+			{
+				Methods: []string{http.MethodGet},
+				Path:    "/problem",
+				JSON:    map[string]any{"title": "bad"},
+				Headers: map[string]string{"Content-Type": "application/problem+json"},
+			},
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/problem", nil))
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+}
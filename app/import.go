@@ -0,0 +1,183 @@
+package app
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+// importSkippedHeaders aren't kept in imported endpoints, they are set by the server or describe
+// the recorded transfer rather than the response.
+var importSkippedHeaders = []string{
+	"Connection", "Content-Encoding", "Content-Length", "Date", "Keep-Alive", "Set-Cookie", "Transfer-Encoding",
+}
+
+var errUnknownImport = errors.New("neither HAR, gomock capture, Postman collection nor cURL commands")
+
+// ImportFile converts the HAR, gomock capture, Postman v2.1 collection or cURL commands file to endpoints.
+func ImportFile(file string) ([]*config.Endpoint, error) {
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, fmt.Errorf("reading import: %w", err)
+	}
+
+	var entries []*JournalEntry
+
+	switch {
+	case isCurlCommands(data):
+		entries, err = loadCurl(data)
+	case isPostmanCollection(data):
+		entries, err = loadPostman(data)
+	default:
+		entries, err = decodeCapture(data)
+		if errors.Is(err, errUnknownCapture) {
+			err = errUnknownImport
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return EndpointsOf(entries), nil
+}
+
+// EndpointsOf converts recorded requests to endpoints, the first response recorded for a method and path wins.
+// Requests, which failed without a response, are skipped.
+func EndpointsOf(entries []*JournalEntry) []*config.Endpoint {
+	var (
+		endpoints []*config.Endpoint
+		seen      = map[string]bool{}
+	)
+
+	for _, entry := range entries {
+		if entry.Response.Status == 0 {
+			continue
+		}
+
+		endpoint := endpointOf(entry)
+
+		key := endpoint.RouteKey()
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints
+}
+
+func endpointOf(entry *JournalEntry) *config.Endpoint {
+	endpoint := &config.Endpoint{Path: entry.Request.Path}
+
+	if method := strings.ToUpper(entry.Request.Method); method != http.MethodGet {
+		endpoint.Methods = []string{method}
+	}
+
+	if entry.Response.Status != http.StatusOK {
+		endpoint.Status = entry.Response.Status
+	}
+
+	header := entry.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	endpoint.Cookies = importCookies(header.Values("Set-Cookie"))
+
+	for _, name := range importSkippedHeaders {
+		header.Del(name)
+	}
+
+	body := recordedBody(entry.Response.Body, entry.Response.BodyEncoding)
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+
+	switch {
+	case len(body) == 0:
+	case isJSONMediaType(mediaType):
+		if doc, err := decodeJSON(body); err == nil && doc != nil {
+			endpoint.JSON = doc
+
+			// JSON responses get it anyway.
+			if header.Get("Content-Type") == contentTypeJSON {
+				header.Del("Content-Type")
+			}
+
+			break
+		}
+
+		fallthrough
+	default:
+		// Body is a template, so that bodies with template actions are kept in base64 as well as binary ones.
+		if utf8.Valid(body) && !strings.Contains(string(body), "{{") {
+			endpoint.Body = string(body)
+		} else {
+			endpoint.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+		}
+	}
+
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if strings.Contains(value, "{{") {
+			continue
+		}
+
+		if endpoint.Headers == nil {
+			endpoint.Headers = map[string]string{}
+		}
+
+		endpoint.Headers[name] = value
+	}
+
+	return endpoint
+}
+
+// importCookies converts Set-Cookie headers to cookies of the endpoint.
+func importCookies(lines []string) []*config.Cookie {
+	var cookies []*config.Cookie
+
+	for _, line := range lines {
+		cookie, err := http.ParseSetCookie(line)
+		if err != nil || strings.Contains(cookie.Value, "{{") {
+			continue
+		}
+
+		imported := &config.Cookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			MaxAge:   cookie.MaxAge,
+			Secure:   cookie.Secure,
+			HTTPOnly: cookie.HttpOnly,
+		}
+
+		if !cookie.Expires.IsZero() {
+			imported.Expires = cookie.Expires.UTC().Format(http.TimeFormat)
+		}
+
+		switch cookie.SameSite {
+		case http.SameSiteLaxMode:
+			imported.SameSite = "lax"
+		case http.SameSiteStrictMode:
+			imported.SameSite = "strict"
+		case http.SameSiteNoneMode:
+			imported.SameSite = "none"
+		case http.SameSiteDefaultMode:
+		}
+
+		cookies = append(cookies, imported)
+	}
+
+	return cookies
+}
@@ -0,0 +1,273 @@
+package app_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock/app"
+)
+
+const importHAR = `{"log": {"version": "1.2", "creator": {"name": "devtools", "version": "1"}, "entries": [
+	{"startedDateTime": "2024-01-01T00:00:00Z", "time": 1,
+	 "request": {"method": "GET", "url": "https://api.example.com/users?page=1"},
+	 "response": {"status": 200, "headers": [
+			{"name": "content-type", "value": "application/json; charset=utf-8"},
+			{"name": "content-encoding", "value": "br"},
+			{"name": "set-cookie", "value": "session=abc; Path=/; HttpOnly; SameSite=Lax"},
+			{"name": "x-request-id", "value": "r1"}
+		],
+		"content": {"mimeType": "application/json", "text": "[{\"id\":1,\"name\":\"<b>Ann</b>\"}]"}}},
+	{"startedDateTime": "2024-01-01T00:00:01Z", "time": 1,
+	 "request": {"method": "GET", "url": "https://api.example.com/users?page=2"},
+	 "response": {"status": 200, "content": {"mimeType": "application/json", "text": "[{\"id\":2}]"}}},
+	{"startedDateTime": "2024-01-01T00:00:02Z", "time": 1,
+	 "request": {"method": "POST", "url": "https://api.example.com/orders",
+		"postData": {"mimeType": "application/xml", "text": "<order/>"}},
+	 "response": {"status": 201, "headers": [{"name": "Content-Type", "value": "application/xml"}],
+		"content": {"mimeType": "application/xml", "text": "<order id=\"1\"/>"}}},
+	{"startedDateTime": "2024-01-01T00:00:03Z", "time": 1,
+	 "request": {"method": "GET", "url": "https://api.example.com/logo.png"},
+	 "response": {"status": 200, "headers": [{"name": "Content-Type", "value": "image/png"}],
+		"content": {"mimeType": "image/png", "text": "iVBORw0KGgo=", "encoding": "base64"}}},
+	{"startedDateTime": "2024-01-01T00:00:04Z", "time": 0,
+	 "request": {"method": "GET", "url": "https://api.example.com/blocked"},
+	 "response": {"status": 0, "content": {"mimeType": "x-unknown"}}}
+]}}`
+
+func TestEndpointsOf_HAR(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "devtools.har")
+	require.NoError(t, os.WriteFile(file, []byte(importHAR), 0o600))
+
+	entries, err := app.LoadCapture(file)
+	require.NoError(t, err)
+
+	endpoints := app.EndpointsOf(entries)
+	require.Len(t, endpoints, 3)
+
+	users := endpoints[0]
+	assert.Equal(t, "/users", users.Path)
+	assert.Empty(t, users.Methods)
+	assert.Equal(t, map[string]string{
+		"Content-Type": "application/json; charset=utf-8",
+		"X-Request-Id": "r1",
+	}, users.Headers)
+	require.Len(t, users.Cookies, 1)
+	assert.Equal(t, "session", users.Cookies[0].Name)
+	assert.Equal(t, "lax", users.Cookies[0].SameSite)
+	assert.True(t, users.Cookies[0].HTTPOnly)
+
+	orders := endpoints[1]
+	assert.Equal(t, []string{"POST"}, orders.Methods)
+	assert.Equal(t, http.StatusCreated, orders.Status)
+	assert.Equal(t, `<order id="1"/>`, orders.Body)
+
+	assert.Equal(t, "iVBORw0KGgo=", endpoints[2].BodyBase64)
+
+	// Imported endpoints reproduce recorded responses.
+	data, err := json.Marshal(map[string]any{"endpoints": endpoints})
+	require.NoError(t, err)
+
+	handler := newMockHandler(t, dir, string(data))
+
+	rec := serveWith(handler, "/users", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id": 1, "name": "<b>Ann</b>"}]`, rec.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Set-Cookie"), "session=abc")
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("<order/>"))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `<order id="1"/>`, rec.Body.String())
+	assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+}
+
+func TestJournal_SaveHAR(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("gomock ", 100)
+
+	journal := app.NewJournal(0)
+	handler := journal.Middleware(newMockHandler(t, t.TempDir(), `{"endpoints": [
+		{"path": "/text", "body": "`+body+`", "compression": {"encodings": ["gzip"]}},
+		{"path": "/orders", "methods": ["POST"], "status": 201, "json": {"id": 1}}
+	]}`))
+
+	serveWith(handler, "/text?lang=en", map[string]string{"Accept-Encoding": "gzip", "Cookie": "session=abc"})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"item": "book"}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	file := filepath.Join(t.TempDir(), "traffic.har")
+	require.NoError(t, journal.Save(file))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	var har struct {
+		Log struct {
+			Version string `json:"version"`
+			Entries []struct {
+				Request struct {
+					URL         string `json:"url"`
+					Cookies     []map[string]string
+					QueryString []map[string]string `json:"queryString"`
+					PostData    *struct {
+						MimeType string `json:"mimeType"`
+						Text     string `json:"text"`
+					} `json:"postData"`
+				} `json:"request"`
+				Response struct {
+					Status  int `json:"status"`
+					Content struct {
+						Size        int    `json:"size"`
+						Text        string `json:"text"`
+						Compression int    `json:"compression"`
+					} `json:"content"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+
+	require.NoError(t, json.Unmarshal(data, &har))
+	assert.Equal(t, "1.2", har.Log.Version)
+	require.Len(t, har.Log.Entries, 2)
+
+	text := har.Log.Entries[0]
+	assert.Equal(t, "http://example.com/text?lang=en", text.Request.URL)
+	assert.Equal(t, []map[string]string{{"name": "session", "value": "abc"}}, text.Request.Cookies)
+	assert.Equal(t, []map[string]string{{"name": "lang", "value": "en"}}, text.Request.QueryString)
+	assert.Equal(t, body, text.Response.Content.Text)
+	assert.Equal(t, len(body), text.Response.Content.Size)
+	assert.Greater(t, text.Response.Content.Compression, 0)
+
+	orders := har.Log.Entries[1]
+	assert.Equal(t, http.StatusCreated, orders.Response.Status)
+	require.NotNil(t, orders.Request.PostData)
+	assert.Equal(t, "application/json", orders.Request.PostData.MimeType)
+	assert.JSONEq(t, `{"item": "book"}`, orders.Request.PostData.Text)
+
+	// Exported HAR can be replayed and imported.
+	entries, err := app.LoadCapture(file)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, body, entries[0].Response.Body)
+	assert.Empty(t, entries[0].Response.Header.Get("Content-Encoding"))
+}
+
+func writeImport(t *testing.T, name, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+
+	return file
+}
+
+func TestImportFile_Postman(t *testing.T) {
+	t.Parallel()
+
+	file := writeImport(t, "collection.json", `{
+		"info": {"name": "Shop", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+		"item": [
+			{"name": "Users", "item": [
+				{"name": "Get user",
+				 "request": {"method": "GET", "url": {"raw": "{{baseUrl}}/users/:id", "host": ["{{baseUrl}}"],
+					"path": ["users", ":id"]}},
+				 "response": [
+					{"name": "Not found", "code": 404, "body": "{\"error\": \"not found\"}",
+					 "_postman_previewlanguage": "json"},
+					{"name": "Found", "code": 200, "body": "{\"id\": 1}", "_postman_previewlanguage": "json",
+					 "header": [{"key": "X-Total", "value": "1"}, {"key": "X-Debug", "value": "1", "disabled": true}]}
+				 ]}
+			]},
+			{"name": "Create order",
+			 "request": {"method": "POST", "url": "https://api.example.com/orders?dry=1"},
+			 "response": [
+				{"name": "Created", "originalRequest": {"method": "POST", "url": "https://api.example.com/orders"},
+				 "code": 201, "header": [{"key": "Content-Type", "value": "text/plain"}], "body": "created"}
+			 ]},
+			{"name": "Without examples", "request": "https://api.example.com/ping"}
+		]
+	}`)
+
+	endpoints, err := app.ImportFile(file)
+	require.NoError(t, err)
+	require.Len(t, endpoints, 2)
+
+	user := endpoints[0]
+	assert.Equal(t, "/users/{id}", user.Path)
+	assert.Equal(t, 0, user.Status)
+	assert.Equal(t, map[string]any{"id": json.Number("1")}, user.JSON)
+	assert.Equal(t, map[string]string{"X-Total": "1"}, user.Headers)
+
+	order := endpoints[1]
+	assert.Equal(t, "/orders", order.Path)
+	assert.Equal(t, []string{"POST"}, order.Methods)
+	assert.Equal(t, http.StatusCreated, order.Status)
+	assert.Equal(t, "created", order.Body)
+}
+
+func TestImportFile_Curl(t *testing.T) {
+	t.Parallel()
+
+	file := writeImport(t, "requests.sh", `# copied from devtools and docs
+curl 'https://api.example.com/users?page=1' \
+  -H 'accept: application/json' \
+  -H $'x-note: it\'s é' \
+  --compressed
+curl -sS -X PUT "https://api.example.com/users/1" -d "{\"name\": \"Ann\"}"
+curl -XDELETE https://api.example.com/users/1; curl --json '{"a": 1}' api.example.com/orders
+curl -sI https://api.example.com/health
+curl -G -d page=2 --url https://api.example.com/users
+curl -F file=@logo.png -u user:secret https://api.example.com/uploads
+echo done
+`)
+
+	endpoints, err := app.ImportFile(file)
+	require.NoError(t, err)
+
+	routes := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		routes = append(routes, endpoint.RouteKey())
+
+		assert.Zero(t, endpoint.Status, endpoint.Path)
+		assert.Nil(t, endpoint.JSON, endpoint.Path)
+		assert.Empty(t, endpoint.Body, endpoint.Path)
+	}
+
+	assert.Equal(t, []string{
+		"GET /users",
+		"PUT /users/1",
+		"DELETE /users/1",
+		"POST /orders",
+		"HEAD /health",
+		"POST /uploads",
+	}, routes)
+}
+
+func TestImportFile_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := app.ImportFile(writeImport(t, "mock.json", `{"endpoints": []}`))
+	assert.Error(t, err)
+
+	_, err = app.ImportFile(writeImport(t, "requests.sh", `curl 'https://api.example.com/users`))
+	assert.Error(t, err)
+
+	_, err = app.ImportFile(writeImport(t, "requests.sh", `curl -H`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const bodyEncodingBase64 = "base64"
+
+// defaultJournalBodyLimit is the number of bytes of request and response bodies kept in the journal.
+const defaultJournalBodyLimit = 1 << 20
+
+// Journal records requests served by the mock server together with their responses.
+type Journal struct {
+	lock      sync.RWMutex
+	entries   []*JournalEntry
+	limit     int // max number of entries kept, the oldest are dropped first, unlimited if 0
+	bodyLimit int
+}
+
+// JournalEntry is a recorded request and its response.
+type JournalEntry struct {
+	Time     time.Time        `json:"time"`
+	Duration time.Duration    `json:"duration"`
+	Endpoint string           `json:"endpoint,omitempty"` // path of the endpoint, which served the request
+	Kind     string           `json:"kind,omitempty"`     // how the request was served: mock, injected, proxy, static, fallback, replay, auth, builtin or preflight
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request recorded in the journal.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Host   string      `json:"host,omitempty"`
+	URL    string      `json:"url"` // request URI, i.e. path with query
+	Path   string      `json:"path"`
+	Query  url.Values  `json:"query,omitempty"`
+	Header http.Header `json:"headers,omitempty"`
+	Body   string      `json:"body,omitempty"`
+
+	BodyEncoding string `json:"bodyEncoding,omitempty"` // "base64" for binary bodies
+	Truncated    bool   `json:"truncated,omitempty"`    // body is cut off at the body limit of the journal
+}
+
+// RecordedResponse is a response recorded in the journal.
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"headers,omitempty"`
+	Body   string      `json:"body,omitempty"`
+
+	BodyEncoding string `json:"bodyEncoding,omitempty"` // "base64" for binary bodies
+	Truncated    bool   `json:"truncated,omitempty"`    // body is cut off at the body limit of the journal
+}
+
+// Matched tells if the request was served by one of the endpoints.
+func (e *JournalEntry) Matched() bool {
+	return e.Endpoint != ""
+}
+
+// NewJournal creates a journal, which keeps at most limit entries, unlimited if limit is 0.
+func NewJournal(limit int) *Journal {
+	return &Journal{limit: limit, bodyLimit: defaultJournalBodyLimit}
+}
+
+// SetBodyLimit sets the number of bytes of request and response bodies kept in the journal, bodies are kept
+// whole if limit is 0, e.g. for recordings, which are replayed later. Must be called before serving requests.
+func (j *Journal) SetBodyLimit(limit int) {
+	if limit <= 0 {
+		limit = math.MaxInt
+	}
+
+	j.bodyLimit = limit
+}
+
+// Middleware returns the journal middleware handler.
+func (j *Journal) Middleware(next http.Handler) http.Handler {
+	if j == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		// Shares the info with the access log, if it is enabled.
+		info, ok := req.Context().Value(accessInfoKey{}).(*accessInfo)
+		if !ok {
+			info = &accessInfo{}
+			req = req.WithContext(context.WithValue(req.Context(), accessInfoKey{}, info))
+		}
+
+		reqBody, truncated, _ := peekBodyPrefix(req, j.bodyLimit)
+		body, encoding := recordBody(reqBody)
+
+		entry := &JournalEntry{
+			Time: start,
+			Request: RecordedRequest{
+				Method:       req.Method,
+				Host:         req.Host,
+				URL:          req.RequestURI,
+				Path:         req.URL.Path,
+				Query:        req.URL.Query(),
+				Header:       req.Header.Clone(),
+				Body:         body,
+				BodyEncoding: encoding,
+				Truncated:    truncated,
+			},
+		}
+
+		recorder := &accessWriter{ResponseWriter: writer, body: &limitedBuffer{limit: j.bodyLimit}}
+
+		next.ServeHTTP(recorder, req)
+
+		status := recorder.status
+		if status == 0 && !recorder.hijacked {
+			status = http.StatusOK
+		}
+
+		entry.Duration = time.Since(start)
+		entry.Endpoint = info.endpoint
+		entry.Kind = info.kind
+		body, encoding = recordBody(recorder.body.Bytes())
+
+		entry.Response = RecordedResponse{
+			Status:       status,
+			Header:       writer.Header().Clone(),
+			Body:         body,
+			BodyEncoding: encoding,
+			Truncated:    recorder.body.truncated,
+		}
+
+		j.add(entry)
+	})
+}
+
+func (j *Journal) add(entry *JournalEntry) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.entries = append(j.entries, entry)
+
+	if j.limit > 0 && len(j.entries) > j.limit {
+		j.entries = j.entries[len(j.entries)-j.limit:]
+	}
+}
+
+// Entries returns recorded entries of completed requests in the order the requests were received.
+func (j *Journal) Entries() []*JournalEntry {
+	j.lock.RLock()
+	entries := slices.Clone(j.entries)
+	j.lock.RUnlock()
+
+	slices.SortStableFunc(entries, func(a, b *JournalEntry) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	return entries
+}
+
+// recordBody keeps text bodies as they are and encodes binary ones with base64.
+func recordBody(data []byte) (string, string) {
+	if utf8.Valid(data) {
+		return string(data), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(data), bodyEncodingBase64
+}
+
+// recordedBody decodes the recorded body.
+func recordedBody(body, encoding string) []byte {
+	if encoding != bodyEncodingBase64 {
+		return []byte(body)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return []byte(body)
+	}
+
+	return data
+}
+
+// Reset removes all recorded entries.
+func (j *Journal) Reset() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.entries = nil
+}
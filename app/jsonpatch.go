@@ -0,0 +1,307 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+var (
+	errJSONPatch      = errors.New("error in applying JSON patch")
+	errJSONPointer    = errors.New("invalid JSON pointer")
+	errPatchTest      = errors.New("test failed")
+	errPatchUnknownOp = errors.New("unknown operation")
+	errPatchNoValue   = errors.New("value is required")
+)
+
+// decodeJSON decodes the JSON keeping numbers as they are, so that patched documents don't lose precision.
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, fmt.Errorf("decoding JSON: %w", err)
+	}
+
+	return value, nil
+}
+
+// copyJSON returns a deep copy of the value in the same form decodeJSON returns.
+func copyJSON(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("encoding JSON: %w", err)
+	}
+
+	return decodeJSON(data)
+}
+
+// applyJSONPatch applies RFC 6902 JSON Patch operations to the document, the patch fails as a whole.
+func applyJSONPatch(doc any, ops []*config.JSONPatchOp) (any, error) {
+	for idx, op := range ops {
+		var err error
+
+		doc, err = applyJSONPatchOp(doc, op)
+		if err != nil {
+			return nil, fmt.Errorf("%w: operation #%d [%s %s]: %w", errJSONPatch, idx+1, op.Op, op.Path, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func applyJSONPatchOp(doc any, op *config.JSONPatchOp) (any, error) {
+	tokens, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace":
+		value, err := patchValue(op)
+		if err != nil {
+			return nil, err
+		}
+
+		return pointerSet(doc, tokens, value, op.Op == "replace")
+	case "remove":
+		return pointerRemove(doc, tokens)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := pointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			doc, err = pointerRemove(doc, from)
+		} else {
+			value, err = copyJSON(value)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return pointerSet(doc, tokens, value, false)
+	case "test":
+		actual, err := pointerGet(doc, tokens)
+		if err != nil {
+			return nil, err
+		}
+
+		expected, err := patchValue(op)
+		if err != nil {
+			return nil, err
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			return nil, fmt.Errorf("%w, value is %v", errPatchTest, actual)
+		}
+
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("%w [%s]", errPatchUnknownOp, op.Op)
+	}
+}
+
+// patchValue decodes the value of the operation, a missing value is an error, while null is a value.
+func patchValue(op *config.JSONPatchOp) (any, error) {
+	if len(op.Value) == 0 {
+		return nil, errPatchNoValue
+	}
+
+	return decodeJSON(op.Value)
+}
+
+// parsePointer splits the RFC 6901 JSON Pointer into unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w [%s]", errJSONPointer, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for idx, token := range tokens {
+		tokens[idx] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func pointerGet(doc any, tokens []string) (any, error) {
+	for _, token := range tokens {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: no member [%s]", errJSONPointer, token)
+			}
+
+			doc = value
+		case []any:
+			idx, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+
+			doc = node[idx]
+		default:
+			return nil, fmt.Errorf("%w: [%s] of a scalar", errJSONPointer, token)
+		}
+	}
+
+	return doc, nil
+}
+
+// pointerSet adds the value, or replaces the existing one, at the location and returns the updated document.
+func pointerSet(doc any, tokens []string, value any, replace bool) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	return updateParent(doc, tokens, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			if _, ok := node[token]; replace && !ok {
+				return nil, fmt.Errorf("%w: no member [%s]", errJSONPointer, token)
+			}
+
+			node[token] = value
+
+			return node, nil
+		case []any:
+			if token == "-" && !replace {
+				return append(node, value), nil
+			}
+
+			limit := len(node)
+			if replace {
+				limit--
+			}
+
+			idx, err := arrayIndex(token, limit)
+			if err != nil {
+				return nil, err
+			}
+
+			if replace {
+				node[idx] = value
+
+				return node, nil
+			}
+
+			return append(node[:idx], append([]any{value}, node[idx:]...)...), nil
+		default:
+			return nil, fmt.Errorf("%w: [%s] of a scalar", errJSONPointer, token)
+		}
+	})
+}
+
+// pointerRemove removes the value at the location and returns the updated document.
+func pointerRemove(doc any, tokens []string) (any, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	return updateParent(doc, tokens, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("%w: no member [%s]", errJSONPointer, token)
+			}
+
+			delete(node, token)
+
+			return node, nil
+		case []any:
+			idx, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+
+			return append(node[:idx], node[idx+1:]...), nil
+		default:
+			return nil, fmt.Errorf("%w: [%s] of a scalar", errJSONPointer, token)
+		}
+	})
+}
+
+// updateParent walks to the parent of the location and replaces it with the result of the update,
+// as arrays may be reallocated.
+func updateParent(doc any, tokens []string, update func(parent any, token string) (any, error)) (any, error) {
+	if len(tokens) == 1 {
+		return update(doc, tokens[0])
+	}
+
+	child, err := pointerGet(doc, tokens[:1])
+	if err != nil {
+		return nil, err
+	}
+
+	child, err = updateParent(child, tokens[1:], update)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node := doc.(type) {
+	case map[string]any:
+		node[tokens[0]] = child
+	case []any:
+		idx, _ := strconv.Atoi(tokens[0])
+		node[idx] = child
+	}
+
+	return doc, nil
+}
+
+// arrayIndex parses the array index, which can't exceed the limit.
+func arrayIndex(token string, limit int) (int, error) {
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || idx > limit || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index [%s]", errJSONPointer, token)
+	}
+
+	return idx, nil
+}
+
+// applyMergePatch applies RFC 7386 JSON Merge Patch to the document.
+func applyMergePatch(doc, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	docObject, ok := doc.(map[string]any)
+	if !ok {
+		docObject = map[string]any{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(docObject, name)
+
+			continue
+		}
+
+		docObject[name] = applyMergePatch(docObject[name], value)
+	}
+
+	return docObject
+}
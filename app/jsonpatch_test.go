@@ -0,0 +1,98 @@
+package app //nolint:testpackage // testing unexported JSON patch internals
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+func patchJSON(t *testing.T, doc, patch string) (string, error) {
+	t.Helper()
+
+	var ops []*config.JSONPatchOp
+	require.NoError(t, json.Unmarshal([]byte(patch), &ops))
+
+	value, err := decodeJSON([]byte(doc))
+	require.NoError(t, err)
+
+	value, err = applyJSONPatch(value, ops)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+
+	return string(data), nil
+}
+
+func Test_applyJSONPatch(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		patch    string
+		expected string
+	}{
+		"add member":      {`[{"op": "add", "path": "/b", "value": {"c": 1}}]`, `{"a": [1, 2], "b": {"c": 1}, "id": 9007199254740993}`},
+		"add to array":    {`[{"op": "add", "path": "/a/1", "value": 3}]`, `{"a": [1, 3, 2], "id": 9007199254740993}`},
+		"append to array": {`[{"op": "add", "path": "/a/-", "value": 3}]`, `{"a": [1, 2, 3], "id": 9007199254740993}`},
+		"replace":         {`[{"op": "replace", "path": "/a/0", "value": "x"}]`, `{"a": ["x", 2], "id": 9007199254740993}`},
+		"remove":          {`[{"op": "remove", "path": "/a/0"}]`, `{"a": [2], "id": 9007199254740993}`},
+		"move":            {`[{"op": "move", "from": "/a", "path": "/b"}]`, `{"b": [1, 2], "id": 9007199254740993}`},
+		"copy":            {`[{"op": "copy", "from": "/a/1", "path": "/a/0"}]`, `{"a": [2, 1, 2], "id": 9007199254740993}`},
+		"test":            {`[{"op": "test", "path": "/a/1", "value": 2}, {"op": "remove", "path": "/id"}]`, `{"a": [1, 2]}`},
+		"escaped":         {`[{"op": "add", "path": "/x~1y~0", "value": true}]`, `{"a": [1, 2], "id": 9007199254740993, "x/y~": true}`},
+		"root":            {`[{"op": "replace", "path": "", "value": []}]`, `[]`},
+		"add null":        {`[{"op": "add", "path": "/b", "value": null}]`, `{"a": [1, 2], "b": null, "id": 9007199254740993}`},
+		"replace by null": {`[{"op": "replace", "path": "/a", "value": null}]`, `{"a": null, "id": 9007199254740993}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := patchJSON(t, `{"a": [1, 2], "id": 9007199254740993}`, tc.patch)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expected, actual)
+		})
+	}
+}
+
+func Test_applyJSONPatch_Errors(t *testing.T) {
+	t.Parallel()
+
+	for name, patch := range map[string]string{
+		"failed test":         `[{"op": "test", "path": "/a/0", "value": 2}]`,
+		"missing member":      `[{"op": "replace", "path": "/b", "value": 2}]`,
+		"index out of bounds": `[{"op": "add", "path": "/a/3", "value": 2}]`,
+		"leading zero":        `[{"op": "remove", "path": "/a/01"}]`,
+		"invalid pointer":     `[{"op": "remove", "path": "a"}]`,
+		"unknown operation":   `[{"op": "merge", "path": "/a"}]`,
+		"add without value":   `[{"op": "add", "path": "/b"}]`,
+		"test without value":  `[{"op": "test", "path": "/a"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := patchJSON(t, `{"a": [1, 2]}`, patch)
+			assert.True(t, errors.Is(err, errJSONPatch), err)
+		})
+	}
+}
+
+func Test_applyMergePatch(t *testing.T) {
+	t.Parallel()
+
+	doc, err := decodeJSON([]byte(`{"a": "b", "c": {"d": "e", "f": "g"}, "h": [1]}`))
+	require.NoError(t, err)
+
+	patch, err := decodeJSON([]byte(`{"a": "z", "c": {"f": null}, "h": {"i": 1}}`))
+	require.NoError(t, err)
+
+	data, err := json.Marshal(applyMergePatch(doc, patch))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": "z", "c": {"d": "e"}, "h": {"i": 1}}`, string(data))
+}
@@ -0,0 +1,467 @@
+package app
+
+import (
+	"bytes"
+	"cmp"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	defaultTokenPath = "/token"
+	defaultJWKSPath  = "/.well-known/jwks.json"
+	defaultTokenTTL  = time.Hour
+
+	jwtAlgorithm = "RS256"
+	jwtKeyBits   = 2048
+	jwtParts     = 3 // header, payload and signature
+)
+
+var (
+	errNoIssuer          = errors.New(`"auth.jwt" requires the "jwt" issuer or "oidc"`)
+	errInsufficientScope = errors.New("insufficient scope")
+	errSigningKey        = errors.New("not an RSA private key")
+)
+
+// issuer signs JWTs with RS256 and publishes the public key of their signatures as JWKS.
+type issuer struct {
+	cfg   *config.JWT
+	key   *rsa.PrivateKey
+	keyID string
+	ttl   time.Duration
+	log   *slog.Logger
+}
+
+// jwtHeader is the JOSE header of a JWT.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// jwk is an RSA public key of JWKS, see RFC 7517.
+type jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// tokenResponse is the response of the token endpoint, see RFC 6749.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"` //nolint:tagliatelle // defined by OAuth 2.0
+	TokenType   string `json:"token_type"`   //nolint:tagliatelle // defined by OAuth 2.0
+	ExpiresIn   int64  `json:"expires_in"`   //nolint:tagliatelle // defined by OAuth 2.0
+}
+
+// setupIssuer mounts the token endpoint and JWKS of the JWT issuer and the OIDC provider,
+// returns nil if neither is configured. The OIDC provider uses the default issuer if "jwt" isn't set.
+func setupIssuer(mockPath string, mck *config.Mock, router *chi.Mux) *issuer {
+	if mck.JWT == nil && mck.OIDC == nil {
+		return nil
+	}
+
+	cfg := mck.JWT
+	if cfg == nil {
+		cfg = &config.JWT{}
+	}
+
+	logger := slog.Default().With("endpoint", "jwt")
+
+	iss, err := newIssuer(mockPath, cfg, logger)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error in setting up JWT issuer: %v", err))
+
+		return nil
+	}
+
+	token, jwks := appHandler(iss.tokenHandler), appHandler(iss.jwksHandler)
+
+	if mck.OIDC != nil {
+		setupOIDC(mck.OIDC, iss, token, jwks, router)
+
+		return iss
+	}
+
+	router.Method(http.MethodPost, iss.tokenPath(), token)
+	router.Method(http.MethodGet, iss.jwksPath(), jwks)
+
+	return iss
+}
+
+func newIssuer(mockPath string, cfg *config.JWT, log *slog.Logger) (*issuer, error) {
+	ttl := defaultTokenTTL
+
+	if cfg.TTL != "" {
+		parsed, err := time.ParseDuration(cfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ttl: %w", err)
+		}
+
+		ttl = parsed
+	}
+
+	key, err := loadSigningKey(mockPath, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &issuer{cfg: cfg, key: key, keyID: thumbprint(&key.PublicKey), ttl: ttl, log: log}, nil
+}
+
+// loadSigningKey reads PKCS #1 or PKCS #8 PEM RSA private key, generates a new key if the file is empty.
+func loadSigningKey(mockPath, file string) (*rsa.PrivateKey, error) {
+	if file == "" {
+		key, err := rsa.GenerateKey(rand.Reader, jwtKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("generating signing key: %w", err)
+		}
+
+		return key, nil
+	}
+
+	data, err := readFile(mockPath, file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s", errSigningKey, file)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errSigningKey, file, err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errSigningKey, file)
+	}
+
+	return key, nil
+}
+
+func (i *issuer) tokenPath() string {
+	return cmp.Or(i.cfg.TokenPath, defaultTokenPath)
+}
+
+func (i *issuer) jwksPath() string {
+	return cmp.Or(i.cfg.JWKSPath, defaultJWKSPath)
+}
+
+// url returns the "iss" claim of tokens, the URL of the server the request came to by default.
+func (i *issuer) url(req *http.Request) string {
+	if i.cfg.Issuer != "" {
+		return i.cfg.Issuer
+	}
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + req.Host
+}
+
+// tokenHandler issues a token with claims of the JSON or form body of the request, they override
+// the default claims, including "exp".
+func (i *issuer) tokenHandler(writer http.ResponseWriter, req *http.Request) *appError {
+	setAccessInfo(req, i.tokenPath(), kindMock)
+
+	claims := map[string]any{}
+
+	data, err := peekBody(req)
+	if err != nil {
+		return &appError{Error: err, Message: err.Error(), Code: http.StatusBadRequest, Log: i.log}
+	}
+
+	if len(bytes.TrimSpace(data)) > 0 {
+		input, form, appErr := readRequestInput(req)
+		if appErr != nil {
+			appErr.Log = i.log
+
+			return appErr
+		}
+
+		form.close()
+
+		claims = input
+	}
+
+	token, expiresIn, err := i.issue(req, claims)
+	if err != nil {
+		return &appError{
+			Error:   err,
+			Message: fmt.Sprintf("error in signing token: %v", err),
+			Code:    http.StatusInternalServerError,
+			Log:     i.log,
+		}
+	}
+
+	writer.Header().Set("Cache-Control", "no-store")
+
+	return writeResponse(writer, tokenResponse{
+		AccessToken: token,
+		TokenType:   schemeBearer,
+		ExpiresIn:   int64(expiresIn.Seconds()),
+	})
+}
+
+func (i *issuer) jwksHandler(writer http.ResponseWriter, req *http.Request) *appError {
+	setAccessInfo(req, i.jwksPath(), kindMock)
+
+	return writeResponse(writer, map[string][]jwk{"keys": {publicJWK(&i.key.PublicKey, i.keyID)}})
+}
+
+// issue signs a token with the default claims overridden by the given ones, returns the token and its lifetime.
+func (i *issuer) issue(req *http.Request, claims map[string]any) (string, time.Duration, error) {
+	now := time.Now()
+
+	all := map[string]any{
+		"iss": i.url(req),
+		"iat": now.Unix(),
+		"exp": now.Add(i.ttl).Unix(),
+	}
+
+	maps.Copy(all, i.cfg.Claims)
+	maps.Copy(all, claims)
+
+	token, err := i.sign(all)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var expiresIn time.Duration
+	if exp, ok := numericClaim(all, "exp"); ok {
+		expiresIn = max(0, time.Unix(int64(exp), 0).Sub(now))
+	}
+
+	return token, expiresIn, nil
+}
+
+func (i *issuer) sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: jwtAlgorithm, Typ: "JWT", Kid: i.keyID})
+	if err != nil {
+		return "", fmt.Errorf("encoding JWT header: %w", err)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("encoding JWT claims: %w", err)
+	}
+
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	signature, err := rsa.SignPKCS1v15(nil, i.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing JWT: %w", err)
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verify checks the signature, expiry and "nbf" of the token, returns its claims.
+func (i *issuer) verify(token string) (map[string]any, error) {
+	header, claims, err := decodeJWT(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Alg != jwtAlgorithm || header.Kid != "" && header.Kid != i.keyID {
+		return nil, fmt.Errorf("%w: unknown signing key", errInvalidToken)
+	}
+
+	dot := strings.LastIndexByte(token, '.')
+	digest := sha256.Sum256([]byte(token[:dot]))
+
+	signature, err := base64.RawURLEncoding.DecodeString(token[dot+1:])
+	if err != nil || rsa.VerifyPKCS1v15(&i.key.PublicKey, crypto.SHA256, digest[:], signature) != nil {
+		return nil, fmt.Errorf("%w: bad signature", errInvalidToken)
+	}
+
+	// Times of other types would silently make the token valid forever.
+	for _, name := range []string{"exp", "nbf"} {
+		_, present := claims[name]
+		if _, ok := numericClaim(claims, name); present && !ok {
+			return nil, fmt.Errorf("%w: %s isn't a number", errInvalidToken, name)
+		}
+	}
+
+	now := time.Now().Unix()
+
+	if exp, ok := numericClaim(claims, "exp"); ok && now >= int64(exp) {
+		return nil, fmt.Errorf("%w: expired", errInvalidToken)
+	}
+
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now < int64(nbf) {
+		return nil, fmt.Errorf("%w: not valid yet", errInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// decodeJWT decodes header and claims of the token without verifying it, numbers become json.Number.
+func decodeJWT(token string) (*jwtHeader, map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != jwtParts {
+		return nil, nil, fmt.Errorf("%w: malformed", errInvalidToken)
+	}
+
+	var (
+		header jwtHeader
+		claims map[string]any
+	)
+
+	for idx, target := range []any{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[idx])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: malformed: %w", errInvalidToken, err)
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+
+		err = decoder.Decode(target)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: malformed: %w", errInvalidToken, err)
+		}
+	}
+
+	return &header, claims, nil
+}
+
+// checkClaims checks issuer, audience and scopes of the verified token.
+func checkClaims(cfg *config.JWTAuth, claims map[string]any) error {
+	if cfg.Issuer != "" && !slices.Contains(claimStrings(claims, "iss"), cfg.Issuer) {
+		return fmt.Errorf("%w: wrong issuer", errInvalidToken)
+	}
+
+	if cfg.Audience != "" && !slices.Contains(claimStrings(claims, "aud"), cfg.Audience) {
+		return fmt.Errorf("%w: wrong audience", errInvalidToken)
+	}
+
+	scopes := tokenScopes(claims)
+
+	for _, scope := range cfg.Scopes {
+		if !slices.Contains(scopes, scope) {
+			return fmt.Errorf("%w: %s is required", errInsufficientScope, scope)
+		}
+	}
+
+	return nil
+}
+
+// tokenScopes returns scopes of the "scope" (space separated) and "scp" (a string or an array) claims.
+func tokenScopes(claims map[string]any) []string {
+	var scopes []string
+
+	for _, value := range append(claimStrings(claims, "scope"), claimStrings(claims, "scp")...) {
+		scopes = append(scopes, strings.Fields(value)...)
+	}
+
+	return scopes
+}
+
+// claimStrings returns the string claim or the strings of the array claim.
+func claimStrings(claims map[string]any, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		var values []string
+
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+
+		return values
+	default:
+		return nil
+	}
+}
+
+// claimMatches tells if the claim has the value, arrays have to contain it.
+func claimMatches(claim any, value string) bool {
+	switch claim := claim.(type) {
+	case nil:
+		return false
+	case []any:
+		return slices.ContainsFunc(claim, func(item any) bool { return claimMatches(item, value) })
+	default:
+		return fmt.Sprint(claim) == value
+	}
+}
+
+func numericClaim(claims map[string]any, name string) (float64, bool) {
+	switch value := claims[name].(type) {
+	case json.Number:
+		num, err := value.Float64()
+
+		return num, err == nil
+	case float64:
+		return value, true
+	case int64:
+		return float64(value), true
+	default:
+		return 0, false
+	}
+}
+
+// bearerToken returns the token of the Authorization header, empty if there is none.
+func bearerToken(req *http.Request) string {
+	scheme, token, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, schemeBearer) {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+func publicJWK(key *rsa.PublicKey, keyID string) jwk {
+	return jwk{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: jwtAlgorithm,
+		Kid: keyID,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// thumbprint returns the JWK thumbprint of the key, see RFC 7638, which is used as its key ID.
+func thumbprint(key *rsa.PublicKey) string {
+	public := publicJWK(key, "")
+	digest := sha256.Sum256([]byte(`{"e":"` + public.E + `","kty":"RSA","n":"` + public.N + `"}`))
+
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
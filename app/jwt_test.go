@@ -0,0 +1,224 @@
+package app_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jwtMock = `{"jwt": {"issuer": "https://id.example.com", "ttl": "10m", "claims": {"aud": "api"}}, "endpoints": [
+	{"path": "/orders", "body": "{{.Principal}} {{.Claims.role}}",
+	 "auth": {"jwt": {"issuer": "https://id.example.com", "audience": "api", "scopes": ["orders:write"]}}},
+	{"path": "/reports", "body": "admin report", "match": {"claims": {"role": "admin"}},
+	 "auth": {"jwt": {}}},
+	{"path": "/reports", "body": "team report", "match": {"claims": {"groups": "team"}}},
+	{"path": "/reports", "status": 404}
+]}`
+
+func issueToken(t *testing.T, handler http.Handler, claims string) string {
+	t.Helper()
+
+	rec := authRequest(handler, http.MethodPost, "/token", claims, map[string]string{"Content-Type": "application/json"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var token struct {
+		AccessToken string `json:"access_token"` //nolint:tagliatelle // defined by OAuth 2.0
+		TokenType   string `json:"token_type"`   //nolint:tagliatelle // defined by OAuth 2.0
+		ExpiresIn   int64  `json:"expires_in"`   //nolint:tagliatelle // defined by OAuth 2.0
+	}
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &token))
+	assert.Equal(t, "Bearer", token.TokenType)
+
+	return token.AccessToken
+}
+
+func TestJWT_IssueAndJWKS(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), jwtMock)
+
+	token := issueToken(t, handler, `{"sub": "alice", "scope": "orders:read orders:write"}`)
+
+	rec := authRequest(handler, http.MethodGet, "/.well-known/jwks.json", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var jwks struct {
+		Keys []struct {
+			Kty, Alg, Kid, N, E string
+		} `json:"keys"`
+	}
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jwks))
+	require.Len(t, jwks.Keys, 1)
+
+	key := jwks.Keys[0]
+	assert.Equal(t, "RSA", key.Kty)
+	assert.Equal(t, "RS256", key.Alg)
+
+	// The token verifies against the published key.
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	modulus, err := base64.RawURLEncoding.DecodeString(key.N)
+	require.NoError(t, err)
+	exponent, err := base64.RawURLEncoding.DecodeString(key.E)
+	require.NoError(t, err)
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+
+	public := &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: int(new(big.Int).SetBytes(exponent).Int64())}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	require.NoError(t, rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature))
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(header), `"kid":"`+key.Kid+`"`)
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+
+	var claims map[string]any
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Equal(t, "https://id.example.com", claims["iss"])
+	assert.Equal(t, "api", claims["aud"])
+	assert.Equal(t, "alice", claims["sub"])
+	assert.InDelta(t, 600, claims["exp"].(float64)-claims["iat"].(float64), 1)
+
+	// Form bodies are accepted as well.
+	rec = authRequest(handler, http.MethodPost, "/token", "sub=bob&scope=orders:write",
+		map[string]string{"Content-Type": "application/x-www-form-urlencoded"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestJWT_Validation(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), jwtMock)
+	bearer := func(token string) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+
+	token := issueToken(t, handler, `{"sub": "alice", "role": "buyer", "scp": ["orders:read", "orders:write"]}`)
+	rec := authRequest(handler, http.MethodGet, "/orders", "", bearer(token))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "alice buyer", rec.Body.String())
+
+	token = issueToken(t, handler, `{"sub": "alice", "scope": "orders:read"}`)
+	rec = authRequest(handler, http.MethodGet, "/orders", "", bearer(token))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, `Bearer realm="gomock", error="insufficient_scope", scope="orders:write"`,
+		rec.Header().Get("WWW-Authenticate"))
+
+	for name, claims := range map[string]string{
+		"expired":        `{"sub": "alice", "scope": "orders:write", "exp": 1}`,
+		"not valid yet":  `{"sub": "alice", "scope": "orders:write", "nbf": 4102444800}`,
+		"wrong audience": `{"sub": "alice", "scope": "orders:write", "aud": ["web"]}`,
+		"wrong issuer":   `{"sub": "alice", "scope": "orders:write", "iss": "https://evil.example.com"}`,
+		"string exp":     `{"sub": "alice", "scope": "orders:write", "exp": "4102444800"}`,
+		"string nbf":     `{"sub": "alice", "scope": "orders:write", "nbf": "1"}`,
+	} {
+		rec = authRequest(handler, http.MethodGet, "/orders", "", bearer(issueToken(t, handler, claims)))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, name)
+		assert.Equal(t, `Bearer realm="gomock", error="invalid_token"`, rec.Header().Get("WWW-Authenticate"), name)
+	}
+
+	// Tampered claims break the signature.
+	parts := strings.Split(issueToken(t, handler, `{"sub": "alice", "scope": "orders:write"}`), ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub": "mallory", "scope": "orders:write", "aud": "api"}`))
+	rec = authRequest(handler, http.MethodGet, "/orders", "", bearer(strings.Join(parts, ".")))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "bad signature")
+
+	// Tokens of another issuer aren't accepted.
+	other := newMockHandler(t, t.TempDir(), jwtMock)
+	rec = authRequest(handler, http.MethodGet, "/orders", "", bearer(issueToken(t, other, `{"scope": "orders:write"}`)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestJWT_MatchClaims(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), jwtMock)
+	bearer := func(claims string) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + issueToken(t, handler, claims)}
+	}
+
+	rec := authRequest(handler, http.MethodGet, "/reports", "", bearer(`{"role": "admin"}`))
+	assert.Equal(t, "admin report", rec.Body.String())
+
+	rec = authRequest(handler, http.MethodGet, "/reports", "", bearer(`{"groups": ["dev", "team"]}`))
+	assert.Equal(t, "team report", rec.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, authRequest(handler, http.MethodGet, "/reports", "", bearer(`{}`)).Code)
+	assert.Equal(t, http.StatusNotFound, authRequest(handler, http.MethodGet, "/reports", "", nil).Code)
+}
+
+func TestJWT_KeyFile(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	mock := `{"jwt": {"keyFile": "key.pem", "tokenPath": "/oauth/token"}, "endpoints": [
+		{"path": "/me", "body": "{{.Principal}}", "auth": {"jwt": {"principal": "email"}}}
+	]}`
+
+	// Tokens survive restarts with the same key.
+	first, second := newMockHandler(t, dir, mock), newMockHandler(t, dir, mock)
+
+	rec := authRequest(first, http.MethodPost, "/oauth/token", `{"email": "ann@example.com"}`, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var token struct {
+		AccessToken string `json:"access_token"` //nolint:tagliatelle // defined by OAuth 2.0
+	}
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &token))
+
+	rec = authRequest(second, http.MethodGet, "/me", "", map[string]string{"Authorization": "Bearer " + token.AccessToken})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ann@example.com", rec.Body.String())
+
+	// JWT auth without the issuer can't verify tokens, so the endpoint isn't set up.
+	handler := newMockHandler(t, dir, `{"endpoints": [{"path": "/me", "auth": {"jwt": {}}}]}`)
+	assert.Equal(t, http.StatusNotFound, authRequest(handler, http.MethodGet, "/me", "", nil).Code)
+}
+
+func TestJWT_PerPrincipalRequiresSubject(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"jwt": {}, "auth": {"jwt": {}}, "endpoints": [
+		{"path": "/notes", "dynamic": {"perPrincipal": true, "read": {"json": {"name": "note"}}}},
+		{"path": "/public", "json": {}}
+	]}`)
+
+	bearer := map[string]string{"Authorization": "Bearer " + issueToken(t, handler, `{"scope": "notes"}`)}
+
+	rec := authRequest(handler, http.MethodGet, "/notes", "", bearer)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "no sub claim")
+
+	assert.Equal(t, http.StatusOK, authRequest(handler, http.MethodGet, "/public", "", bearer).Code)
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	distributionFixed     = "fixed"
+	distributionUniform   = "uniform"
+	distributionNormal    = "normal"
+	distributionLogNormal = "lognormal"
+
+	// z99 is the standard normal quantile of the 99th percentile.
+	z99 = 2.326347874
+)
+
+var (
+	errUnsupportedDistribution = errors.New("unsupported latency distribution")
+	errUniformBounds           = errors.New("min of uniform latency is greater than max")
+)
+
+// latency samples delays from the configured latency profile.
+type latency struct {
+	cfg config.Latency
+	rnd *random
+}
+
+// newLatency resolves latency of the endpoint, falling back to its fixed delay and then to the global profile.
+func newLatency(endpoint *config.Endpoint, global *config.Latency, rnd *random) (*latency, error) {
+	var lat *latency
+
+	switch {
+	case endpoint.Latency != nil:
+		lat = &latency{cfg: *endpoint.Latency, rnd: rnd}
+	case endpoint.Delay > 0:
+		lat = &latency{cfg: config.Latency{Delay: endpoint.Delay}, rnd: rnd}
+	case global != nil:
+		lat = &latency{cfg: *global, rnd: rnd}
+	default:
+		return nil, nil
+	}
+
+	switch lat.cfg.Distribution {
+	case distributionUniform:
+		if lat.cfg.Min > lat.cfg.Max {
+			return nil, fmt.Errorf("%w: min [%d], max [%d]", errUniformBounds, lat.cfg.Min, lat.cfg.Max)
+		}
+
+		return lat, nil
+	case "", distributionFixed, distributionNormal, distributionLogNormal:
+		return lat, nil
+	default:
+		return nil, fmt.Errorf("%w [%s]", errUnsupportedDistribution, lat.cfg.Distribution)
+	}
+}
+
+// sample returns next delay.
+func (l *latency) sample() time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	var millis float64
+
+	switch l.cfg.Distribution {
+	case distributionUniform:
+		millis = float64(l.cfg.Min) + l.rnd.Float64()*float64(l.cfg.Max-l.cfg.Min)
+	case distributionNormal:
+		millis = l.normal()
+	case distributionLogNormal:
+		millis = l.logNormal()
+	default:
+		millis = float64(l.cfg.Delay)
+		if l.cfg.Jitter > 0 {
+			millis += (l.rnd.Float64()*2 - 1) * float64(l.cfg.Jitter)
+		}
+	}
+
+	millis = max(millis, float64(l.cfg.Min), 0)
+	if l.cfg.Max > 0 {
+		millis = min(millis, float64(l.cfg.Max))
+	}
+
+	return time.Duration(millis * float64(time.Millisecond))
+}
+
+func (l *latency) normal() float64 {
+	mean := l.cfg.Mean
+	if mean == 0 {
+		mean = l.cfg.P50
+	}
+
+	stdDev := l.cfg.StdDev
+	if stdDev == 0 && l.cfg.P99 > 0 {
+		stdDev = (l.cfg.P99 - mean) / z99
+	}
+
+	return mean + l.rnd.NormFloat64()*stdDev
+}
+
+func (l *latency) logNormal() float64 {
+	if l.cfg.P50 <= 0 {
+		return 0
+	}
+
+	mu := math.Log(l.cfg.P50)
+
+	sigma := 0.0
+	if l.cfg.P99 > l.cfg.P50 {
+		sigma = (math.Log(l.cfg.P99) - mu) / z99
+	}
+
+	return math.Exp(mu + l.rnd.NormFloat64()*sigma)
+}
+
+// wait blocks for the next sampled delay or until ctx is done.
+func (l *latency) wait(ctx context.Context) {
+	delay := l.sample()
+	if delay <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
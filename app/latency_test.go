@@ -0,0 +1,108 @@
+package app //nolint:testpackage // testing unexported latency internals
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+func mustLatency(t *testing.T, endpoint *config.Endpoint, global *config.Latency, rnd *random) *latency {
+	t.Helper()
+
+	lat, err := newLatency(endpoint, global, rnd)
+	require.NoError(t, err)
+
+	return lat
+}
+
+func Test_Latency_Precedence(t *testing.T) {
+	t.Parallel()
+
+	global := &config.Latency{Delay: 30}
+	rnd := newRandom(1, 0)
+
+	assert.Nil(t, mustLatency(t, &config.Endpoint{}, nil, rnd))
+	assert.Equal(t, 30*time.Millisecond, mustLatency(t, &config.Endpoint{}, global, rnd).sample())
+	assert.Equal(t, 20*time.Millisecond, mustLatency(t, &config.Endpoint{Delay: 20}, global, rnd).sample())
+	assert.Equal(t, 10*time.Millisecond,
+		mustLatency(t, &config.Endpoint{Delay: 20, Latency: &config.Latency{Delay: 10}}, global, rnd).sample())
+}
+
+func Test_Latency_SeedIsReproducible(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Latency{Distribution: distributionNormal, Mean: 100, StdDev: 20}
+	first := mustLatency(t, &config.Endpoint{Latency: cfg}, nil, newRandom(42, 3))
+	second := mustLatency(t, &config.Endpoint{Latency: cfg}, nil, newRandom(42, 3))
+
+	for range 10 {
+		assert.Equal(t, first.sample(), second.sample())
+	}
+}
+
+func Test_Latency_Bounds(t *testing.T) {
+	t.Parallel()
+
+	uniform := mustLatency(t, &config.Endpoint{Latency: &config.Latency{
+		Distribution: distributionUniform, Min: 10, Max: 20,
+	}}, nil, newRandom(7, 0))
+
+	jitter := mustLatency(t, &config.Endpoint{Latency: &config.Latency{Delay: 100, Jitter: 10}}, nil, newRandom(7, 0))
+
+	for range 100 {
+		delay := uniform.sample()
+		assert.True(t, delay >= 10*time.Millisecond && delay <= 20*time.Millisecond, delay)
+
+		delay = jitter.sample()
+		assert.True(t, delay >= 90*time.Millisecond && delay <= 110*time.Millisecond, delay)
+	}
+}
+
+func Test_Latency_LogNormalPercentiles(t *testing.T) {
+	t.Parallel()
+
+	lat := mustLatency(t, &config.Endpoint{Latency: &config.Latency{
+		Distribution: distributionLogNormal, P50: 50, P99: 500,
+	}}, nil, newRandom(11, 0))
+
+	const samples = 20000
+
+	delays := make([]time.Duration, samples)
+	for idx := range delays {
+		delays[idx] = lat.sample()
+	}
+
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+
+	assert.InDelta(t, 50, delays[samples/2].Milliseconds(), 5)
+	assert.InDelta(t, 500, delays[samples*99/100].Milliseconds(), 75)
+}
+
+func Test_Latency_UnsupportedDistribution(t *testing.T) {
+	t.Parallel()
+
+	_, err := newLatency(&config.Endpoint{Latency: &config.Latency{Distribution: "poisson", Delay: 10}}, nil,
+		newRandom(1, 0))
+	assert.True(t, errors.Is(err, errUnsupportedDistribution), err)
+
+	_, err = newLatency(&config.Endpoint{}, &config.Latency{Distribution: "Uniform"}, newRandom(1, 0))
+	assert.True(t, errors.Is(err, errUnsupportedDistribution), err)
+}
+
+func Test_Latency_UniformBounds(t *testing.T) {
+	t.Parallel()
+
+	_, err := newLatency(&config.Endpoint{Latency: &config.Latency{Distribution: distributionUniform, Min: 20, Max: 10}},
+		nil, newRandom(1, 0))
+	assert.True(t, errors.Is(err, errUniformBounds), err)
+
+	_, err = newLatency(&config.Endpoint{Latency: &config.Latency{Distribution: distributionUniform, Min: 10, Max: 10}},
+		nil, newRandom(1, 0))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,251 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+// matcher checks if a request meets the conditions of an endpoint.
+type matcher struct {
+	headers    map[string]string
+	soapAction string
+	xpaths     []xpathCondition
+	form       map[string]string
+	claims     map[string]string
+}
+
+type xpathCondition struct {
+	xpath *xpath
+	value string
+}
+
+// newMatcher compiles conditions of the endpoint, returns nil if it accepts any request.
+func newMatcher(cfg *config.Match) (*matcher, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	match := &matcher{
+		headers:    cfg.Headers,
+		soapAction: cfg.SOAPAction,
+		form:       cfg.Form,
+		claims:     cfg.Claims,
+	}
+
+	for expr, value := range cfg.XPath {
+		compiled, err := compileXPath(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		match.xpaths = append(match.xpaths, xpathCondition{xpath: compiled, value: value})
+	}
+
+	return match, nil
+}
+
+func (m *matcher) matches(input *matchInput) bool {
+	if m == nil {
+		return true
+	}
+
+	for name, value := range m.headers {
+		if input.req.Header.Get(name) != value {
+			return false
+		}
+	}
+
+	if m.soapAction != "" && soapAction(input.req) != m.soapAction {
+		return false
+	}
+
+	if len(m.xpaths) > 0 {
+		doc := input.xml()
+		if doc == nil {
+			return false
+		}
+
+		for _, cond := range m.xpaths {
+			if !slices.Contains(cond.xpath.eval(doc), cond.value) {
+				return false
+			}
+		}
+	}
+
+	if len(m.form) > 0 {
+		values := input.formValues()
+
+		for name, value := range m.form {
+			if values.Get(name) != value {
+				return false
+			}
+		}
+	}
+
+	if len(m.claims) > 0 {
+		claims := input.tokenClaims()
+
+		for name, value := range m.claims {
+			if !claimMatches(claims[name], value) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// matchInput lazily reads and parses the body of the request being matched against endpoints.
+type matchInput struct {
+	req    *http.Request
+	parsed bool
+	doc    *xmlNode
+
+	formParsed bool
+	form       url.Values
+
+	claimsParsed bool
+	claims       map[string]any
+}
+
+func (in *matchInput) xml() *xmlNode {
+	if in.parsed {
+		return in.doc
+	}
+
+	in.parsed = true
+
+	data, err := peekBody(in.req)
+	if err != nil {
+		return nil
+	}
+
+	in.doc, _ = parseXML(data)
+
+	return in.doc
+}
+
+func (in *matchInput) formValues() url.Values {
+	if in.formParsed {
+		return in.form
+	}
+
+	in.formParsed = true
+
+	form, err := readRequestForm(in.req)
+	if err != nil || form == nil {
+		return in.form
+	}
+
+	in.form = form.values
+	form.close()
+
+	return in.form
+}
+
+// tokenClaims returns claims of the bearer JWT of the request, it isn't verified, since matching only selects
+// the endpoint and "auth.jwt" of the endpoint verifies the token.
+func (in *matchInput) tokenClaims() map[string]any {
+	if !in.claimsParsed {
+		in.claimsParsed = true
+		_, in.claims, _ = decodeJWT(bearerToken(in.req))
+	}
+
+	return in.claims
+}
+
+// routeGroup dispatches requests of a route to the first of its endpoints, which accepts the request.
+type routeGroup struct {
+	router    *chi.Mux
+	endpoints []*routeEndpoint
+	allowed   []string
+}
+
+type routeEndpoint struct {
+	methods []string // nil allows any method
+	matcher *matcher
+	cors    bool
+	handler http.Handler
+}
+
+func (g *routeGroup) add(endpoint *routeEndpoint) {
+	g.endpoints = append(g.endpoints, endpoint)
+
+	for _, method := range endpoint.methods {
+		if !slices.Contains(g.allowed, method) {
+			g.allowed = append(g.allowed, method)
+		}
+	}
+}
+
+func (g *routeGroup) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	input := &matchInput{req: req}
+	methodAllowed := false
+
+	for _, endpoint := range g.endpoints {
+		if endpoint.methods != nil && !slices.Contains(endpoint.methods, req.Method) {
+			continue
+		}
+
+		methodAllowed = true
+
+		if endpoint.matcher.matches(input) {
+			endpoint.handler.ServeHTTP(writer, req)
+
+			return
+		}
+	}
+
+	if methodAllowed {
+		g.router.NotFoundHandler().ServeHTTP(writer, req)
+
+		return
+	}
+
+	// CORS preflight requests are answered by the CORS middleware of the endpoint.
+	if req.Method == http.MethodOptions {
+		for _, endpoint := range g.endpoints {
+			if endpoint.cors {
+				endpoint.handler.ServeHTTP(writer, req)
+
+				return
+			}
+		}
+	}
+
+	for _, method := range g.allowed {
+		writer.Header().Add("Allow", method)
+	}
+
+	g.router.MethodNotAllowedHandler().ServeHTTP(writer, req)
+}
+
+// RouteMatches tells if the path matches the endpoint path with "{param}" segments and "*" wildcards,
+// a trailing "*" matches the rest of the path.
+func RouteMatches(pattern, path string) bool {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+
+	for idx, part := range patternParts {
+		if part == "*" && idx == len(patternParts)-1 {
+			return true
+		}
+
+		if idx >= len(pathParts) {
+			return false
+		}
+
+		wildcard := part == "*" || (strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}"))
+		if (wildcard && pathParts[idx] == "") || (!wildcard && part != pathParts[idx]) {
+			return false
+		}
+	}
+
+	return len(patternParts) == len(pathParts)
+}
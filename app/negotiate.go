@@ -0,0 +1,144 @@
+package app
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+// qualityValue is an item of Accept-like header with its weight.
+type qualityValue struct {
+	value   string
+	quality float64
+}
+
+// parseQualityValues parses Accept-like header, e.g. "text/html, application/json;q=0.9".
+func parseQualityValues(header string) []qualityValue {
+	var values []qualityValue
+
+	for item := range strings.SplitSeq(header, ",") {
+		value, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		if value == "" {
+			continue
+		}
+
+		quality := 1.0
+
+		for param := range strings.SplitSeq(params, ";") {
+			name, raw, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+					quality = parsed
+				}
+			}
+		}
+
+		values = append(values, qualityValue{value: strings.ToLower(strings.TrimSpace(value)), quality: quality})
+	}
+
+	return values
+}
+
+// variant is a response body of an endpoint for a media type.
+type variant struct {
+	mediaType string
+	body      *responseBody
+}
+
+// variants is a set of bodies of an endpoint negotiated by Accept.
+type variants []*variant
+
+// loadVariants resolves variants of the endpoint, returns nil if the endpoint has none.
+func loadVariants(mockPath string, endpoint *config.Endpoint) (variants, error) {
+	if len(endpoint.Variants) == 0 {
+		return nil, nil
+	}
+
+	result := make(variants, 0, len(endpoint.Variants))
+
+	for _, cfg := range endpoint.Variants {
+		mediaType, _, err := mime.ParseMediaType(cfg.Type)
+		if err != nil {
+			return nil, fmt.Errorf("parsing variant type [%s]: %w", cfg.Type, err)
+		}
+
+		body, err := loadBody(mockPath, &config.Endpoint{
+			JSON:       cfg.JSON,
+			JSONPath:   cfg.JSONPath,
+			Body:       cfg.Body,
+			BodyBase64: cfg.BodyBase64,
+			BodyFile:   cfg.BodyFile,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("loading variant [%s]: %w", cfg.Type, err)
+		}
+
+		if body == nil {
+			body = &responseBody{}
+		}
+
+		body.contentType = cfg.Type
+		result = append(result, &variant{mediaType: mediaType, body: body})
+	}
+
+	return result, nil
+}
+
+// negotiate returns the body preferred by Accept of the request, ties go to the first defined variant,
+// returns nil if the request accepts none of them.
+func (v variants) negotiate(req *http.Request) *responseBody {
+	accept := req.Header.Get("Accept")
+	if accept == "" {
+		return v[0].body
+	}
+
+	ranges := parseQualityValues(accept)
+
+	var (
+		best        *responseBody
+		bestQuality float64
+	)
+
+	for _, candidate := range v {
+		quality := mediaRangeQuality(ranges, candidate.mediaType)
+		if quality > bestQuality {
+			best, bestQuality = candidate.body, quality
+		}
+	}
+
+	return best
+}
+
+// mediaRangeQuality returns the weight of the most specific media range, which matches the media type.
+func mediaRangeQuality(ranges []qualityValue, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+
+	quality, specificity := 0.0, -1
+
+	for _, mediaRange := range ranges {
+		rangeType, _, _ := strings.Cut(mediaRange.value, ";")
+
+		var level int
+
+		switch rangeType {
+		case mediaType:
+			level = 2
+		case mainType + "/*":
+			level = 1
+		case "*/*", "*":
+			level = 0
+		default:
+			continue
+		}
+
+		if level > specificity {
+			quality, specificity = mediaRange.quality, level
+		}
+	}
+
+	return quality
+}
@@ -0,0 +1,141 @@
+package app_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveWith(handler http.Handler, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestNegotiate_Variants(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"endpoints": [
+		{"path": "/users", "variants": [
+			{"type": "application/json", "json": [{"id": 1}]},
+			{"type": "application/xml", "body": "<users><user id=\"1\"/></users>"},
+			{"type": "text/csv", "body": "id\n1\n"}
+		]}
+	]}`)
+
+	for _, tc := range []struct {
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"", http.StatusOK, "application/json"},
+		{"application/xml", http.StatusOK, "application/xml"},
+		{"text/*", http.StatusOK, "text/csv"},
+		{"application/json;q=0.5, text/csv;q=0.8", http.StatusOK, "text/csv"},
+		{"application/*;q=0.9, application/xml;q=0.1", http.StatusOK, "application/json"},
+		{"*/*", http.StatusOK, "application/json"},
+		{"image/png", http.StatusNotAcceptable, "text/plain; charset=utf-8"},
+		{"application/json;q=0, */*", http.StatusOK, "application/xml"},
+	} {
+		rec := serveWith(handler, "/users", map[string]string{"Accept": tc.accept})
+
+		assert.Equal(t, tc.status, rec.Code, tc.accept)
+		assert.Equal(t, tc.contentType, rec.Header().Get("Content-Type"), tc.accept)
+		assert.Equal(t, "Accept", rec.Header().Get("Vary"), tc.accept)
+	}
+}
+
+func TestNegotiate_Compression(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("gomock ", 100)
+
+	handler := newMockHandler(t, t.TempDir(), `{"compression": {"minSize": 100}, "endpoints": [
+		{"path": "/large", "body": "`+body+`"},
+		{"path": "/small", "body": "gomock"},
+		{"path": "/gzip", "body": "`+body+`", "compression": {"encodings": ["gzip"]}}
+	]}`)
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+
+	for _, tc := range []struct {
+		path           string
+		acceptEncoding string
+		encoding       string
+	}{
+		{"/large", "gzip, deflate, br, zstd", "br"},
+		{"/large", "gzip;q=0.5, zstd", "zstd"},
+		{"/large", "gzip", "gzip"},
+		{"/large", "*;q=0.1, br;q=0", "zstd"},
+		{"/large", "zstd", "zstd"}, // reuses the encoder
+		{"/large", "deflate", ""},
+		{"/large", "", ""},
+		{"/small", "gzip", ""},
+		{"/gzip", "br, gzip;q=0.1", "gzip"},
+	} {
+		rec := serveWith(handler, tc.path, map[string]string{"Accept-Encoding": tc.acceptEncoding})
+
+		assert.Equal(t, http.StatusOK, rec.Code, tc.acceptEncoding)
+		assert.Equal(t, tc.encoding, rec.Header().Get("Content-Encoding"), tc.acceptEncoding)
+		assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"), tc.acceptEncoding)
+
+		if tc.encoding == "" {
+			assert.NotEmpty(t, rec.Body.String(), tc.acceptEncoding)
+
+			continue
+		}
+
+		assert.Empty(t, rec.Header().Get("Content-Length"), tc.acceptEncoding)
+
+		reader, err := decoders[tc.encoding](bytes.NewReader(rec.Body.Bytes()))
+		require.NoError(t, err)
+
+		decoded, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(decoded), tc.acceptEncoding)
+	}
+}
+
+func TestNegotiate_StaticPrecompressed(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := filepath.Join(dir, "files")
+	require.NoError(t, os.MkdirAll(files, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(files, "app.js"), []byte("plain"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(files, "app.js.gz"), []byte("gzipped"), 0o600))
+
+	handler := newMockHandler(t, dir, `{"endpoints": [{"path": "/files/*", "static": "`+dir+`"}]}`)
+
+	rec := serveWith(handler, "/files/app.js", map[string]string{"Accept-Encoding": "br, gzip"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "text/javascript; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "gzipped", rec.Body.String())
+
+	rec = serveWith(handler, "/files/app.js", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "plain", rec.Body.String())
+}
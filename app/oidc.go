@@ -0,0 +1,680 @@
+package app
+
+import (
+	"cmp"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	defaultAuthorizePath = "/authorize"
+	defaultUserinfoPath  = "/userinfo"
+	discoveryPath        = "/.well-known/openid-configuration"
+
+	grantAuthorizationCode = "authorization_code"
+	grantRefreshToken      = "refresh_token"
+	grantClientCredentials = "client_credentials"
+
+	challengeS256  = "S256"
+	challengePlain = "plain"
+	scopeOpenID    = "openid"
+
+	// codeTTL is the lifetime of authorization codes.
+	codeTTL = 10 * time.Minute
+	// refreshTTL is the lifetime of refresh tokens.
+	refreshTTL = 24 * time.Hour
+)
+
+var (
+	errUnknownClient = errors.New("unknown client")
+	errRedirectURI   = errors.New("invalid redirect_uri")
+)
+
+// loginForm is the page of the authorization endpoint, which signs users in.
+var loginForm = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Sign in</title></head>
+<body>
+<form method="post" action="{{.Action}}">
+<h1>Sign in to {{.Client}}</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+{{range $name, $values := .Params}}{{range $values}}<input type="hidden" name="{{$name}}" value="{{.}}">
+{{end}}{{end}}<p><label>Username <input name="username" value="{{.Username}}" autofocus></label></p>
+<p><label>Password <input name="password" type="password"></label></p>
+<p><button type="submit">Sign in</button></p>
+</form>
+</body>
+</html>
+`))
+
+// oauthError is an error response of OAuth 2.0, see RFC 6749.
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"` //nolint:tagliatelle // defined by OAuth 2.0
+	status      int
+}
+
+func (e *oauthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(status int, code, description string) *oauthError {
+	return &oauthError{Code: code, Description: description, status: status}
+}
+
+// oidcTokenResponse is the response of the token endpoint to OAuth 2.0 grants.
+type oidcTokenResponse struct {
+	tokenResponse
+
+	IDToken      string `json:"id_token,omitempty"`      //nolint:tagliatelle // defined by OpenID Connect
+	RefreshToken string `json:"refresh_token,omitempty"` //nolint:tagliatelle // defined by OAuth 2.0
+	Scope        string `json:"scope,omitempty"`
+}
+
+// authCode is an issued authorization code, codes can be exchanged for tokens once.
+type authCode struct {
+	client      *config.OIDCClient
+	user        *config.OIDCUser
+	redirectURI string
+	redirected  bool // redirect_uri was sent to the authorization endpoint, so the token request has to repeat it
+	scope       string
+	nonce       string
+	challenge   string
+	method      string
+	expires     time.Time
+}
+
+// refreshGrant is what an issued refresh token grants.
+type refreshGrant struct {
+	client  *config.OIDCClient
+	user    *config.OIDCUser
+	scope   string // scope granted by the user, access tokens of a refresh can have a narrower one
+	expires time.Time
+}
+
+// oidcProvider is OAuth 2.0 and OpenID Connect provider, its tokens are signed by the issuer.
+type oidcProvider struct {
+	cfg    *config.OIDC
+	issuer *issuer
+	log    *slog.Logger
+
+	mu      sync.Mutex
+	codes   map[string]*authCode
+	refresh map[string]*refreshGrant
+}
+
+// setupOIDC mounts endpoints of the provider, the token endpoint passes requests without "grant_type"
+// to the token endpoint of the issuer.
+func setupOIDC(cfg *config.OIDC, iss *issuer, token, jwks http.Handler, router *chi.Mux) {
+	provider := &oidcProvider{
+		cfg:     cfg,
+		issuer:  iss,
+		log:     slog.Default().With("endpoint", "oidc"),
+		codes:   map[string]*authCode{},
+		refresh: map[string]*refreshGrant{},
+	}
+
+	origins := cfg.AllowCors
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+
+	cors := NewCORS(origins...)
+
+	mount := func(path string, handler http.Handler, methods ...string) {
+		handler = cors.endpointMiddleware(path, handler)
+
+		for _, method := range append(methods, http.MethodOptions) {
+			router.Method(method, path, handler)
+		}
+	}
+
+	mount(discoveryPath, appHandler(provider.discoveryHandler), http.MethodGet)
+	mount(provider.authorizePath(), appHandler(provider.authorizeHandler), http.MethodGet, http.MethodPost)
+	mount(iss.tokenPath(), provider.tokenHandler(token), http.MethodPost)
+	mount(provider.userinfoPath(), http.HandlerFunc(provider.userinfoHandler), http.MethodGet, http.MethodPost)
+	mount(iss.jwksPath(), jwks, http.MethodGet)
+}
+
+func (p *oidcProvider) authorizePath() string {
+	return cmp.Or(p.cfg.AuthorizePath, defaultAuthorizePath)
+}
+
+func (p *oidcProvider) userinfoPath() string {
+	return cmp.Or(p.cfg.UserinfoPath, defaultUserinfoPath)
+}
+
+func (p *oidcProvider) discoveryHandler(writer http.ResponseWriter, req *http.Request) *appError {
+	setAccessInfo(req, discoveryPath, kindMock)
+
+	issuerURL := p.issuer.url(req)
+	base := strings.TrimSuffix(issuerURL, "/")
+
+	return writeResponse(writer, map[string]any{
+		"issuer":                                issuerURL,
+		"authorization_endpoint":                base + p.authorizePath(),
+		"token_endpoint":                        base + p.issuer.tokenPath(),
+		"userinfo_endpoint":                     base + p.userinfoPath(),
+		"jwks_uri":                              base + p.issuer.jwksPath(),
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{grantAuthorizationCode, grantRefreshToken, grantClientCredentials},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{jwtAlgorithm},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{challengeS256, challengePlain},
+	})
+}
+
+// authorizeHandler signs the user in and redirects back to the client with an authorization code.
+// Errors of unknown clients and redirect URIs are shown to the user, the others are sent to the client.
+func (p *oidcProvider) authorizeHandler(writer http.ResponseWriter, req *http.Request) *appError {
+	setAccessInfo(req, p.authorizePath(), kindMock)
+
+	err := req.ParseForm()
+	if err != nil {
+		return &appError{Error: err, Message: fmt.Sprintf("wrong request: %v", err), Code: http.StatusBadRequest}
+	}
+
+	params := req.Form
+
+	client := p.client(params.Get("client_id"))
+	if client == nil {
+		return &appError{Error: errUnknownClient, Message: errUnknownClient.Error(), Code: http.StatusBadRequest}
+	}
+
+	redirectURI, err := redirectURIOf(client, params.Get("redirect_uri"))
+	if err != nil {
+		return &appError{Error: err, Message: err.Error(), Code: http.StatusBadRequest}
+	}
+
+	state := params.Get("state")
+	challenge, method := params.Get("code_challenge"), params.Get("code_challenge_method")
+
+	switch {
+	case challenge == "":
+		method = ""
+	case method == "":
+		method = challengePlain
+	}
+
+	var failure *oauthError
+
+	switch {
+	case params.Get("response_type") != "code":
+		failure = newOAuthError(0, "unsupported_response_type", `only "code" is supported`)
+	case challenge == "" && client.Secret == "":
+		failure = newOAuthError(0, "invalid_request", "code_challenge is required for public clients")
+	case challenge != "" && method != challengeS256 && method != challengePlain:
+		failure = newOAuthError(0, "invalid_request", "unsupported code_challenge_method")
+	case !scopeAllowed(client, params.Get("scope")):
+		failure = newOAuthError(0, "invalid_scope", "scope isn't allowed for the client")
+	}
+
+	if failure != nil {
+		redirectWith(writer, req, redirectURI, url.Values{
+			"error": {failure.Code}, "error_description": {failure.Description}, "state": {state},
+		})
+
+		return nil
+	}
+
+	user, loginErr := p.login(req, params)
+	if user == nil {
+		return p.loginForm(writer, req, client, loginErr)
+	}
+
+	code := rand.Text()
+
+	p.mu.Lock()
+	p.purge()
+	p.codes[code] = &authCode{
+		client:      client,
+		user:        user,
+		redirectURI: redirectURI,
+		redirected:  params.Has("redirect_uri"),
+		scope:       params.Get("scope"),
+		nonce:       params.Get("nonce"),
+		challenge:   challenge,
+		method:      method,
+		expires:     time.Now().Add(codeTTL),
+	}
+	p.mu.Unlock()
+
+	p.log.Debug("authorized", "client", client.ID, "user", user.Username)
+	redirectWith(writer, req, redirectURI, url.Values{"code": {code}, "state": {state}})
+
+	return nil
+}
+
+// login returns the signed in user, "login_hint" or the first user if sign in is approved automatically,
+// the user of the submitted login form otherwise. Returns the reason, if the form has wrong credentials.
+func (p *oidcProvider) login(req *http.Request, params url.Values) (*config.OIDCUser, string) {
+	if p.cfg.AutoApprove && len(p.cfg.Users) > 0 {
+		if user := p.user(params.Get("login_hint")); user != nil {
+			return user, ""
+		}
+
+		return p.cfg.Users[0], ""
+	}
+
+	if req.Method != http.MethodPost || !req.PostForm.Has("username") {
+		return nil, ""
+	}
+
+	user := p.user(req.PostForm.Get("username"))
+	if user == nil || user.Password != req.PostForm.Get("password") {
+		return nil, "Invalid username or password."
+	}
+
+	return user, ""
+}
+
+func (p *oidcProvider) loginForm(writer http.ResponseWriter, req *http.Request, client *config.OIDCClient,
+	loginErr string) *appError {
+	params := url.Values{}
+
+	for name, values := range req.Form {
+		if name != "username" && name != "password" {
+			params[name] = values
+		}
+	}
+
+	status := http.StatusOK
+	if loginErr != "" {
+		status = http.StatusUnauthorized
+	}
+
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(status)
+
+	err := loginForm.Execute(writer, map[string]any{
+		"Action":   req.URL.Path,
+		"Client":   client.ID,
+		"Error":    loginErr,
+		"Params":   params,
+		"Username": req.PostForm.Get("username"),
+	})
+	if err != nil {
+		p.log.Error(fmt.Sprintf("error in rendering login form: %v", err))
+	}
+
+	return nil
+}
+
+// tokenHandler handles grants of OAuth 2.0, requests without "grant_type" are passed to the next handler.
+func (p *oidcProvider) tokenHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		form, err := readRequestForm(req)
+		if err != nil || form == nil || !form.values.Has("grant_type") {
+			next.ServeHTTP(writer, req)
+
+			return
+		}
+
+		form.close()
+		setAccessInfo(req, p.issuer.tokenPath(), kindMock)
+		writer.Header().Set("Cache-Control", "no-store")
+
+		response, err := p.grant(req, form.values)
+		if err != nil {
+			p.log.Debug("rejecting grant", "error", err)
+			writeOAuthError(writer, err)
+
+			return
+		}
+
+		if appErr := writeResponse(writer, response); appErr != nil {
+			p.log.Error(appErr.Message)
+		}
+	})
+}
+
+func (p *oidcProvider) grant(req *http.Request, form url.Values) (*oidcTokenResponse, error) {
+	client, err := p.authenticateClient(req, form)
+	if err != nil {
+		return nil, err
+	}
+
+	switch grant := form.Get("grant_type"); grant {
+	case grantAuthorizationCode:
+		return p.exchangeCode(req, client, form)
+	case grantRefreshToken:
+		return p.refreshTokens(req, client, form)
+	case grantClientCredentials:
+		return p.clientCredentials(req, client, form)
+	default:
+		return nil, newOAuthError(http.StatusBadRequest, "unsupported_grant_type", grant+" isn't supported")
+	}
+}
+
+// authenticateClient checks credentials of the client in the Authorization header or in the form,
+// public clients have no secret.
+func (p *oidcProvider) authenticateClient(req *http.Request, form url.Values) (*config.OIDCClient, error) {
+	clientID, secret, basic := req.BasicAuth()
+	if basic {
+		// Credentials of Basic auth are form-urlencoded, see RFC 6749, section 2.3.1.
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = form.Get("client_id"), form.Get("client_secret")
+	}
+
+	client := p.client(clientID)
+	if client == nil || client.Secret != secret {
+		return nil, newOAuthError(http.StatusUnauthorized, "invalid_client", "unknown client or wrong secret")
+	}
+
+	return client, nil
+}
+
+func (p *oidcProvider) exchangeCode(req *http.Request, client *config.OIDCClient,
+	form url.Values) (*oidcTokenResponse, error) {
+	p.mu.Lock()
+	code := p.codes[form.Get("code")]
+	delete(p.codes, form.Get("code"))
+	p.mu.Unlock()
+
+	switch {
+	case code == nil || code.client != client || time.Now().After(code.expires):
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "unknown or expired code")
+	// redirect_uri is required, if it was sent to the authorization endpoint, see RFC 6749, section 4.1.3.
+	case (code.redirected || form.Has("redirect_uri")) && form.Get("redirect_uri") != code.redirectURI:
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "redirect_uri doesn't match")
+	case !code.verify(form.Get("code_verifier")):
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "code_verifier doesn't match")
+	}
+
+	return p.tokens(req, &refreshGrant{client: client, user: code.user, scope: code.scope}, code.scope, code.nonce)
+}
+
+// purge removes expired codes and refresh tokens, p.mu must be held.
+func (p *oidcProvider) purge() {
+	now := time.Now()
+
+	maps.DeleteFunc(p.codes, func(_ string, code *authCode) bool {
+		return now.After(code.expires)
+	})
+	maps.DeleteFunc(p.refresh, func(_ string, grant *refreshGrant) bool {
+		return now.After(grant.expires)
+	})
+}
+
+// verify checks the PKCE verifier against the challenge, see RFC 7636.
+func (c *authCode) verify(verifier string) bool {
+	switch c.method {
+	case "":
+		return true
+	case challengeS256:
+		digest := sha256.Sum256([]byte(verifier))
+
+		return base64.RawURLEncoding.EncodeToString(digest[:]) == c.challenge
+	default:
+		return verifier == c.challenge
+	}
+}
+
+// refreshTokens exchanges the refresh token for new tokens, the refresh token is rotated.
+func (p *oidcProvider) refreshTokens(req *http.Request, client *config.OIDCClient,
+	form url.Values) (*oidcTokenResponse, error) {
+	token := form.Get("refresh_token")
+
+	p.mu.Lock()
+	grant := p.refresh[token]
+	p.mu.Unlock()
+
+	// The token is checked before it is used up, so that wrong requests, e.g. of other clients, don't revoke it.
+	if grant == nil || grant.client != client || time.Now().After(grant.expires) {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "unknown refresh token")
+	}
+
+	scope := grant.scope
+
+	if requested := form.Get("scope"); requested != "" {
+		granted := strings.Fields(grant.scope)
+
+		for _, name := range strings.Fields(requested) {
+			if !slices.Contains(granted, name) {
+				return nil, newOAuthError(http.StatusBadRequest, "invalid_scope", name+" wasn't granted")
+			}
+		}
+
+		scope = requested
+	}
+
+	p.mu.Lock()
+	// Concurrent requests with the same token get tokens only once.
+	used := p.refresh[token] != grant
+	delete(p.refresh, token)
+	p.mu.Unlock()
+
+	if used {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "unknown refresh token")
+	}
+
+	return p.tokens(req, grant, scope, "")
+}
+
+func (p *oidcProvider) clientCredentials(req *http.Request, client *config.OIDCClient,
+	form url.Values) (*oidcTokenResponse, error) {
+	if client.Secret == "" {
+		return nil, newOAuthError(http.StatusBadRequest, "unauthorized_client", "public clients have no credentials")
+	}
+
+	scope := form.Get("scope")
+	if !scopeAllowed(client, scope) {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_scope", "scope isn't allowed for the client")
+	}
+
+	claims := maps.Clone(client.Claims)
+	if claims == nil {
+		claims = map[string]any{}
+	}
+
+	claims["sub"] = client.ID
+	claims["client_id"] = client.ID
+
+	if scope != "" {
+		claims["scope"] = scope
+	}
+
+	token, expiresIn, err := p.issuer.issue(req, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oidcTokenResponse{
+		tokenResponse: tokenResponse{AccessToken: token, TokenType: schemeBearer, ExpiresIn: int64(expiresIn.Seconds())},
+		Scope:         scope,
+	}, nil
+}
+
+// tokens issues access tokens of the scope, a refresh token of the grant, and an ID token if "openid" scope
+// is issued.
+func (p *oidcProvider) tokens(req *http.Request, grant *refreshGrant, scope,
+	nonce string) (*oidcTokenResponse, error) {
+	client, user := grant.client, grant.user
+
+	claims := userClaims(user)
+	claims["client_id"] = client.ID
+
+	if scope != "" {
+		claims["scope"] = scope
+	}
+
+	token, expiresIn, err := p.issuer.issue(req, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &oidcTokenResponse{
+		tokenResponse: tokenResponse{AccessToken: token, TokenType: schemeBearer, ExpiresIn: int64(expiresIn.Seconds())},
+		RefreshToken:  rand.Text(),
+		Scope:         scope,
+	}
+
+	if slices.Contains(strings.Fields(scope), scopeOpenID) {
+		claims = userClaims(user)
+		claims["aud"] = client.ID
+
+		if nonce != "" {
+			claims["nonce"] = nonce
+		}
+
+		response.IDToken, _, err = p.issuer.issue(req, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	p.purge()
+	p.refresh[response.RefreshToken] = &refreshGrant{
+		client: client, user: user, scope: grant.scope, expires: time.Now().Add(refreshTTL),
+	}
+	p.mu.Unlock()
+
+	return response, nil
+}
+
+// userinfoHandler responds with claims of the user of the bearer access token.
+func (p *oidcProvider) userinfoHandler(writer http.ResponseWriter, req *http.Request) {
+	setAccessInfo(req, p.userinfoPath(), kindMock)
+
+	var user *config.OIDCUser
+
+	claims, err := p.issuer.verify(bearerToken(req))
+	if err == nil {
+		subject, _ := claims["sub"].(string)
+		user = p.user(subject)
+	}
+
+	if user == nil {
+		p.log.Debug("rejecting userinfo request", "error", err)
+		writer.Header().Set("WWW-Authenticate", schemeBearer+` error="invalid_token"`)
+		http.Error(writer, errInvalidToken.Error(), http.StatusUnauthorized)
+
+		return
+	}
+
+	if appErr := writeResponse(writer, userClaims(user)); appErr != nil {
+		p.log.Error(appErr.Message)
+	}
+}
+
+func (p *oidcProvider) client(id string) *config.OIDCClient {
+	for _, client := range p.cfg.Clients {
+		if client.ID == id {
+			return client
+		}
+	}
+
+	return nil
+}
+
+func (p *oidcProvider) user(username string) *config.OIDCUser {
+	for _, user := range p.cfg.Users {
+		if user.Username == username {
+			return user
+		}
+	}
+
+	return nil
+}
+
+// userClaims returns claims of the user with the username as "sub".
+func userClaims(user *config.OIDCUser) map[string]any {
+	claims := maps.Clone(user.Claims)
+	if claims == nil {
+		claims = map[string]any{}
+	}
+
+	claims["sub"] = user.Username
+
+	return claims
+}
+
+// scopeAllowed tells if the client may request the scope, any scope is allowed if the client lists none.
+func scopeAllowed(client *config.OIDCClient, scope string) bool {
+	if len(client.Scopes) == 0 {
+		return true
+	}
+
+	for _, name := range strings.Fields(scope) {
+		if !slices.Contains(client.Scopes, name) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// redirectURIOf returns the redirect URI of the authorization request, which has to be registered
+// for the client, the only registered one is used if the request has none.
+func redirectURIOf(client *config.OIDCClient, redirectURI string) (string, error) {
+	switch {
+	case redirectURI == "" && len(client.RedirectURIs) == 1:
+		return client.RedirectURIs[0], nil
+	case redirectURI == "":
+		return "", fmt.Errorf("%w: it is required", errRedirectURI)
+	case len(client.RedirectURIs) > 0 && !slices.Contains(client.RedirectURIs, redirectURI):
+		return "", fmt.Errorf("%w: %s isn't registered", errRedirectURI, redirectURI)
+	}
+
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || !parsed.IsAbs() {
+		return "", fmt.Errorf("%w: %s isn't an absolute URL", errRedirectURI, redirectURI)
+	}
+
+	return redirectURI, nil
+}
+
+// redirectWith redirects to the URI with the values added to its query, empty values are skipped.
+func redirectWith(writer http.ResponseWriter, req *http.Request, redirectURI string, values url.Values) {
+	target, _ := url.Parse(redirectURI) // checked by redirectURIOf
+
+	query := target.Query()
+
+	for name, value := range values {
+		if value[0] != "" {
+			query[name] = value
+		}
+	}
+
+	target.RawQuery = query.Encode()
+
+	http.Redirect(writer, req, target.String(), http.StatusFound)
+}
+
+func writeOAuthError(writer http.ResponseWriter, err error) {
+	var oauthErr *oauthError
+	if !errors.As(err, &oauthErr) {
+		oauthErr = newOAuthError(http.StatusInternalServerError, "server_error", err.Error())
+	}
+
+	if oauthErr.status == http.StatusUnauthorized {
+		writer.Header().Set("WWW-Authenticate", fmt.Sprintf("%s realm=%q", schemeBasic, defaultRealm))
+	}
+
+	writer.Header().Set("Content-Type", contentTypeJSON)
+	writer.WriteHeader(oauthErr.status)
+
+	_ = json.NewEncoder(writer).Encode(oauthErr)
+}
@@ -0,0 +1,332 @@
+package app_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const oidcMock = `{"oidc": {
+	"users": [
+		{"username": "alice", "password": "secret", "claims": {"email": "alice@example.com", "role": "admin"}},
+		{"username": "bob", "password": "hunter2"}
+	],
+	"clients": [
+		{"id": "spa", "redirectUris": ["https://app.example.com/callback"]},
+		{"id": "backend", "secret": "s3cr3t", "scopes": ["reports:read"], "claims": {"tenant": "acme"}}
+	]
+}, "endpoints": [
+	{"path": "/profile", "body": "{{.Claims.email}}", "auth": {"jwt": {"scopes": ["profile"]}}},
+	{"path": "/reports", "body": "{{.Principal}} {{.Claims.tenant}}", "auth": {"jwt": {"scopes": ["reports:read"]}}}
+]}`
+
+func formRequest(handler http.Handler, method, path string, form url.Values,
+	headers map[string]string) *httptest.ResponseRecorder {
+	all := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+	for name, value := range headers {
+		all[name] = value
+	}
+
+	return authRequest(handler, method, path, form.Encode(), all)
+}
+
+func jwtClaims(t *testing.T, token string) map[string]any {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+
+	var claims map[string]any
+	require.NoError(t, json.Unmarshal(payload, &claims))
+
+	return claims
+}
+
+type oidcTokens struct {
+	AccessToken  string `json:"access_token"`  //nolint:tagliatelle // defined by OAuth 2.0
+	IDToken      string `json:"id_token"`      //nolint:tagliatelle // defined by OpenID Connect
+	RefreshToken string `json:"refresh_token"` //nolint:tagliatelle // defined by OAuth 2.0
+	Scope        string `json:"scope"`
+	Error        string `json:"error"`
+}
+
+func decodeTokens(t *testing.T, rec *httptest.ResponseRecorder) oidcTokens {
+	t.Helper()
+
+	var tokens oidcTokens
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens), rec.Body.String())
+
+	return tokens
+}
+
+func TestOIDC_Discovery(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), oidcMock)
+
+	rec := authRequest(handler, http.MethodGet, "/.well-known/openid-configuration", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "http://example.com", doc["issuer"])
+	assert.Equal(t, "http://example.com/authorize", doc["authorization_endpoint"])
+	assert.Equal(t, "http://example.com/token", doc["token_endpoint"])
+	assert.Equal(t, "http://example.com/userinfo", doc["userinfo_endpoint"])
+	assert.Equal(t, "http://example.com/.well-known/jwks.json", doc["jwks_uri"])
+	assert.Contains(t, doc["code_challenge_methods_supported"], "S256")
+
+	// SPAs call the provider from their own origin.
+	rec = authRequest(handler, http.MethodOptions, "/token", "", map[string]string{
+		"Origin":                        "https://app.example.com",
+		"Access-Control-Request-Method": http.MethodPost,
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	assert.Equal(t, http.StatusOK, authRequest(handler, http.MethodGet, "/.well-known/jwks.json", "", nil).Code)
+}
+
+func TestOIDC_AuthorizationCodeWithPKCE(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), oidcMock)
+
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	digest := sha256.Sum256([]byte(verifier))
+	authorize := "/authorize?" + url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"spa"},
+		"redirect_uri":          {"https://app.example.com/callback"},
+		"scope":                 {"openid profile"},
+		"state":                 {"xyz"},
+		"nonce":                 {"n-0S6"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(digest[:])},
+		"code_challenge_method": {"S256"},
+	}.Encode()
+
+	rec := authRequest(handler, http.MethodGet, authorize, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<input type="hidden" name="state" value="xyz">`)
+	assert.Contains(t, rec.Body.String(), `name="password"`)
+
+	rec = formRequest(handler, http.MethodPost, authorize, url.Values{"username": {"alice"}, "password": {"wrong"}}, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Invalid username or password")
+
+	rec = formRequest(handler, http.MethodPost, authorize, url.Values{"username": {"alice"}, "password": {"secret"}}, nil)
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "app.example.com", location.Host)
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	exchange := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {"https://app.example.com/callback"},
+		"client_id":     {"spa"},
+		"code_verifier": {"wrong-verifier"},
+	}
+
+	rec = formRequest(handler, http.MethodPost, "/token", exchange, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "invalid_grant", decodeTokens(t, rec).Error)
+
+	// redirect_uri sent to the authorization endpoint has to be repeated.
+	rec = formRequest(handler, http.MethodPost, authorize, url.Values{"username": {"alice"}, "password": {"secret"}}, nil)
+	location, err = url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+
+	exchange.Set("code", location.Query().Get("code"))
+	exchange.Set("code_verifier", verifier)
+	exchange.Del("redirect_uri")
+
+	rec = formRequest(handler, http.MethodPost, "/token", exchange, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "invalid_grant", decodeTokens(t, rec).Error)
+
+	// Codes are single use, even after a failed exchange.
+	rec = formRequest(handler, http.MethodPost, authorize, url.Values{"username": {"alice"}, "password": {"secret"}}, nil)
+	location, err = url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+
+	exchange.Set("code", location.Query().Get("code"))
+	exchange.Set("redirect_uri", "https://app.example.com/callback")
+
+	rec = formRequest(handler, http.MethodPost, "/token", exchange, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+	tokens := decodeTokens(t, rec)
+	assert.Equal(t, "openid profile", tokens.Scope)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	idToken := jwtClaims(t, tokens.IDToken)
+	assert.Equal(t, "alice", idToken["sub"])
+	assert.Equal(t, "spa", idToken["aud"])
+	assert.Equal(t, "n-0S6", idToken["nonce"])
+	assert.Equal(t, "alice@example.com", idToken["email"])
+
+	assert.Equal(t, http.StatusBadRequest, formRequest(handler, http.MethodPost, "/token", exchange, nil).Code)
+
+	bearer := map[string]string{"Authorization": "Bearer " + tokens.AccessToken}
+
+	rec = authRequest(handler, http.MethodGet, "/profile", "", bearer)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "alice@example.com", rec.Body.String())
+
+	rec = authRequest(handler, http.MethodGet, "/userinfo", "", bearer)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"sub": "alice", "email": "alice@example.com", "role": "admin"}`, rec.Body.String())
+
+	assert.Equal(t, http.StatusUnauthorized, authRequest(handler, http.MethodGet, "/userinfo", "", nil).Code)
+
+	// Refresh tokens are rotated.
+	refresh := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens.RefreshToken}, "client_id": {"spa"}}
+
+	rec = formRequest(handler, http.MethodPost, "/token", refresh, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	refreshed := decodeTokens(t, rec)
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+	assert.Equal(t, "alice", jwtClaims(t, refreshed.AccessToken)["sub"])
+
+	assert.Equal(t, http.StatusBadRequest, formRequest(handler, http.MethodPost, "/token", refresh, nil).Code)
+
+	// Tokens of other clients are rejected and stay valid.
+	stolen := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refreshed.RefreshToken}}
+	rec = formRequest(handler, http.MethodPost, "/token", stolen, map[string]string{"Authorization": "Basic " +
+		base64.StdEncoding.EncodeToString([]byte("backend:s3cr3t"))})
+	assert.Equal(t, "invalid_grant", decodeTokens(t, rec).Error)
+
+	refresh.Set("refresh_token", refreshed.RefreshToken)
+	refresh.Set("scope", "openid email")
+	rec = formRequest(handler, http.MethodPost, "/token", refresh, nil)
+	assert.Equal(t, "invalid_scope", decodeTokens(t, rec).Error)
+
+	// A narrower scope applies only to the issued access token.
+	refresh.Set("scope", "openid")
+	rec = formRequest(handler, http.MethodPost, "/token", refresh, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	narrowed := decodeTokens(t, rec)
+	assert.Equal(t, "openid", narrowed.Scope)
+
+	refresh.Set("refresh_token", narrowed.RefreshToken)
+	refresh.Set("scope", "openid profile")
+	rec = formRequest(handler, http.MethodPost, "/token", refresh, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "openid profile", decodeTokens(t, rec).Scope)
+}
+
+func TestOIDC_AuthorizeErrors(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), oidcMock)
+
+	rec := authRequest(handler, http.MethodGet, "/authorize?response_type=code&client_id=unknown", "", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = authRequest(handler, http.MethodGet,
+		"/authorize?response_type=code&client_id=spa&redirect_uri=https://evil.example.com/", "", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Public clients have to use PKCE, errors are sent back to the client.
+	rec = authRequest(handler, http.MethodGet, "/authorize?response_type=code&client_id=spa&state=s1", "", nil)
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "/callback", location.Path)
+	assert.Equal(t, "invalid_request", location.Query().Get("error"))
+	assert.Equal(t, "s1", location.Query().Get("state"))
+
+	rec = authRequest(handler, http.MethodGet, "/authorize?response_type=token&client_id=backend&redirect_uri="+
+		url.QueryEscape("https://backend.example.com/cb"), "", nil)
+	require.Equal(t, http.StatusFound, rec.Code)
+	assert.Contains(t, rec.Header().Get("Location"), "error=unsupported_response_type")
+}
+
+func TestOIDC_AutoApprove(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"oidc": {"autoApprove": true,
+		"users": [{"username": "alice"}, {"username": "bob"}],
+		"clients": [{"id": "web", "secret": "s", "redirectUris": ["http://localhost:3000/cb"]}]}}`)
+
+	for hint, user := range map[string]string{"bob": "bob", "": "alice", "nobody": "alice"} {
+		rec := authRequest(handler, http.MethodGet, "/authorize?response_type=code&client_id=web&login_hint="+hint, "", nil)
+		require.Equal(t, http.StatusFound, rec.Code, hint)
+
+		location, err := url.Parse(rec.Header().Get("Location"))
+		require.NoError(t, err)
+
+		rec = formRequest(handler, http.MethodPost, "/token", url.Values{
+			"grant_type": {"authorization_code"}, "code": {location.Query().Get("code")},
+		}, map[string]string{"Authorization": "Basic d2ViOnM="})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		tokens := decodeTokens(t, rec)
+		assert.Equal(t, user, jwtClaims(t, tokens.AccessToken)["sub"], hint)
+		assert.Empty(t, tokens.IDToken, hint)
+	}
+}
+
+func TestOIDC_ClientCredentials(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), oidcMock)
+
+	rec := formRequest(handler, http.MethodPost, "/token", url.Values{
+		"grant_type": {"client_credentials"}, "scope": {"reports:read"},
+		"client_id": {"backend"}, "client_secret": {"s3cr3t"},
+	}, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	tokens := decodeTokens(t, rec)
+	assert.Empty(t, tokens.RefreshToken)
+
+	rec = authRequest(handler, http.MethodGet, "/reports", "", map[string]string{"Authorization": "Bearer " + tokens.AccessToken})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "backend acme", rec.Body.String())
+
+	for name, tc := range map[string]struct {
+		form   url.Values
+		status int
+		error  string
+	}{
+		"wrong secret": {url.Values{"grant_type": {"client_credentials"}, "client_id": {"backend"},
+			"client_secret": {"nope"}}, http.StatusUnauthorized, "invalid_client"},
+		"public client": {url.Values{"grant_type": {"client_credentials"}, "client_id": {"spa"}},
+			http.StatusBadRequest, "unauthorized_client"},
+		"scope": {url.Values{"grant_type": {"client_credentials"}, "client_id": {"backend"},
+			"client_secret": {"s3cr3t"}, "scope": {"admin"}}, http.StatusBadRequest, "invalid_scope"},
+		"grant type": {url.Values{"grant_type": {"password"}, "client_id": {"spa"}},
+			http.StatusBadRequest, "unsupported_grant_type"},
+	} {
+		rec = formRequest(handler, http.MethodPost, "/token", tc.form, nil)
+		assert.Equal(t, tc.status, rec.Code, name)
+		assert.Equal(t, tc.error, decodeTokens(t, rec).Error, name)
+	}
+
+	// Requests without a grant issue tokens with arbitrary claims.
+	rec = authRequest(handler, http.MethodPost, "/token", `{"sub": "carol"}`, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "carol", jwtClaims(t, decodeTokens(t, rec).AccessToken)["sub"])
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// postmanCollection is a Postman collection v2.1, see https://schema.postman.com.
+type postmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item []*postmanItem `json:"item"`
+}
+
+// postmanItem is either a folder of items or a request with its saved examples.
+type postmanItem struct {
+	Name     string             `json:"name"`
+	Item     []*postmanItem     `json:"item,omitempty"`
+	Request  *postmanRequest    `json:"request,omitempty"`
+	Response []*postmanResponse `json:"response,omitempty"`
+}
+
+type postmanRequest struct {
+	Method string          `json:"method"`
+	Header []postmanHeader `json:"header"`
+	URL    postmanURL      `json:"url"`
+}
+
+// UnmarshalJSON accepts the short form of a request, which is just its URL.
+func (r *postmanRequest) UnmarshalJSON(data []byte) error {
+	var raw string
+	if json.Unmarshal(data, &raw) == nil {
+		*r = postmanRequest{Method: http.MethodGet, URL: postmanURL{Raw: raw}}
+
+		return nil
+	}
+
+	type request postmanRequest
+
+	return json.Unmarshal(data, (*request)(r)) //nolint:wrapcheck // decoding error of the collection
+}
+
+type postmanURL struct {
+	Raw  string   `json:"raw"`
+	Path []string `json:"path"`
+}
+
+// UnmarshalJSON accepts the URL as a string as well.
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if json.Unmarshal(data, &raw) == nil {
+		*u = postmanURL{Raw: raw}
+
+		return nil
+	}
+
+	type postman postmanURL
+
+	return json.Unmarshal(data, (*postman)(u)) //nolint:wrapcheck // decoding error of the collection
+}
+
+type postmanResponse struct {
+	Name            string          `json:"name"`
+	OriginalRequest *postmanRequest `json:"originalRequest,omitempty"`
+	Code            int             `json:"code"`
+	Header          []postmanHeader `json:"header"`
+	Body            string          `json:"body"`
+	Language        string          `json:"_postman_previewlanguage"` //nolint:tagliatelle // defined by Postman
+}
+
+type postmanHeader struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// isPostmanCollection tells if the JSON document is a Postman collection.
+func isPostmanCollection(data []byte) bool {
+	var probe struct {
+		Info *struct {
+			Schema string `json:"schema"`
+		} `json:"info"`
+	}
+
+	return json.Unmarshal(data, &probe) == nil && probe.Info != nil &&
+		strings.Contains(probe.Info.Schema, "schema.getpostman.com")
+}
+
+// loadPostman converts saved example responses of the Postman collection to journal entries,
+// successful examples first. Requests without examples are skipped.
+func loadPostman(data []byte) ([]*JournalEntry, error) {
+	var collection postmanCollection
+
+	err := json.Unmarshal(data, &collection)
+	if err != nil {
+		return nil, fmt.Errorf("decoding Postman collection: %w", err)
+	}
+
+	var entries []*JournalEntry
+
+	var walk func(items []*postmanItem)
+
+	walk = func(items []*postmanItem) {
+		for _, item := range items {
+			walk(item.Item)
+
+			examples := slices.Clone(item.Response)
+			slices.SortStableFunc(examples, func(a, b *postmanResponse) int {
+				return cmp.Compare(exampleRank(a.Code), exampleRank(b.Code))
+			})
+
+			for _, example := range examples {
+				entries = append(entries, example.journalEntry(item.Request))
+			}
+		}
+	}
+
+	walk(collection.Item)
+
+	return entries, nil
+}
+
+func (r *postmanResponse) journalEntry(request *postmanRequest) *JournalEntry {
+	if r.OriginalRequest != nil {
+		request = r.OriginalRequest
+	}
+
+	entry := &JournalEntry{
+		Request:  RecordedRequest{Method: http.MethodGet, Path: "/"},
+		Response: RecordedResponse{Status: r.Code, Header: http.Header{}, Body: r.Body},
+	}
+
+	if request != nil {
+		if request.Method != "" {
+			entry.Request.Method = strings.ToUpper(request.Method)
+		}
+
+		entry.Request.Path = request.URL.path()
+	}
+
+	if entry.Response.Status == 0 {
+		entry.Response.Status = http.StatusOK
+	}
+
+	for _, header := range r.Header {
+		if !header.Disabled {
+			entry.Response.Header.Add(header.Key, header.Value)
+		}
+	}
+
+	if entry.Response.Header.Get("Content-Type") == "" && r.Language == "json" {
+		entry.Response.Header.Set("Content-Type", contentTypeJSON)
+	}
+
+	return entry
+}
+
+// path returns the route of the URL, Postman path variables (":id") and variables ("{{id}}")
+// become URL parameters ("{id}").
+func (u *postmanURL) path() string {
+	segments := slices.Clone(u.Path)
+
+	if len(segments) == 0 {
+		raw, _, _ := strings.Cut(u.Raw, "?")
+		raw, _, _ = strings.Cut(raw, "#")
+
+		if _, rest, ok := strings.Cut(raw, "://"); ok {
+			raw = rest
+		}
+
+		// Host, which is often a variable like "{{baseUrl}}", is dropped.
+		_, raw, _ = strings.Cut(raw, "/")
+		segments = strings.Split(raw, "/")
+	}
+
+	for idx, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			segments[idx] = "{" + segment[1:] + "}"
+		case strings.HasPrefix(segment, "{{") && strings.HasSuffix(segment, "}}"):
+			segments[idx] = "{" + strings.TrimSuffix(strings.TrimPrefix(segment, "{{"), "}}") + "}"
+		default:
+			if unescaped, err := url.PathUnescape(segment); err == nil {
+				segments[idx] = unescaped
+			}
+		}
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
+// exampleRank orders successful examples before the others.
+func exampleRank(status int) int {
+	if status == 0 || status >= http.StatusOK && status < http.StatusMultipleChoices {
+		return 0
+	}
+
+	return 1
+}
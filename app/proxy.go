@@ -8,6 +8,8 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"strings"
+
+	"github.com/smeshkov/gomock/config"
 )
 
 // Proxy wraps a reverse proxy with URL rewriting.
@@ -15,6 +17,8 @@ type Proxy struct {
 	host   *url.URL
 	target *url.URL
 	proxy  *httputil.ReverseProxy
+	rules  *proxyRules
+	chaos  *proxyChaos
 	log    *slog.Logger
 }
 
@@ -28,7 +32,8 @@ const (
 	redirectMaxCode = 400
 )
 
-func newProxy(serverAddr, target string, log *slog.Logger) (*Proxy, error) {
+func newProxy(serverAddr, target string, rulesCfg *config.ProxyRules, transport http.RoundTripper,
+	log *slog.Logger) (*Proxy, error) {
 	hostURL, err := url.Parse("http://localhost" + serverAddr)
 	if err != nil {
 		return nil, fmt.Errorf("%w [%s]", errParseHostURL, serverAddr)
@@ -39,12 +44,56 @@ func newProxy(serverAddr, target string, log *slog.Logger) (*Proxy, error) {
 		return nil, fmt.Errorf("%w [%s]", errParseProxyURL, target)
 	}
 
-	return &Proxy{
+	rules, err := newProxyRules(rulesCfg)
+	if err != nil {
+		return nil, fmt.Errorf("error in compiling proxy rules: %w", err)
+	}
+
+	proxy := &Proxy{
 		host:   hostURL,
 		target: proxyURL,
 		proxy:  httputil.NewSingleHostReverseProxy(proxyURL),
+		rules:  rules,
 		log:    log,
-	}, nil
+	}
+
+	proxy.proxy.Transport = transport
+	proxy.proxy.ModifyResponse = proxy.modifyResponse
+	proxy.proxy.ErrorHandler = proxy.handleError
+
+	return proxy, nil
+}
+
+// modifyResponse fails over to the next upstream on failover statuses, applies rules and chaos otherwise.
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	if attempt := upstreamAttemptOf(resp.Request); attempt != nil && attempt.failsOn(resp.StatusCode) {
+		return fmt.Errorf("%w [%d]", errUpstreamStatus, resp.StatusCode)
+	}
+
+	if p.rules != nil {
+		err := p.rules.modifyResponse(resp)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.chaos != nil {
+		return p.chaos.modifyResponse(resp)
+	}
+
+	return nil
+}
+
+func (p *Proxy) handleError(writer http.ResponseWriter, req *http.Request, err error) {
+	// Failed attempts of upstreams are retried with the next target, nothing is written.
+	if attempt := upstreamAttemptOf(req); attempt != nil {
+		attempt.err = err
+
+		return
+	}
+
+	p.log.Error("error in proxying call", "target", p.target.String(), "error", err)
+	writer.WriteHeader(http.StatusBadGateway)
 }
 
 func (p *Proxy) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
@@ -63,6 +112,10 @@ func (p *Proxy) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
 
 	req.URL.RawQuery = newReqQuery
 
+	if p.rules != nil {
+		req = p.rules.rewriteRequest(req)
+	}
+
 	p.log.Debug("proxying call", "target", req.RequestURI)
 	p.proxy.ServeHTTP(wrapper, req)
 
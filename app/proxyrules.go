@@ -0,0 +1,229 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+// proxyRules transforms proxied requests and upstream responses.
+type proxyRules struct {
+	setRequestHeaders     map[string]*valueTemplate
+	removeRequestHeaders  []string
+	stripPrefix           string
+	rewrites              []*pathRewrite
+	status                int
+	setResponseHeaders    map[string]*valueTemplate
+	removeResponseHeaders []string
+	jsonPatch             []*config.JSONPatchOp
+	mergePatch            []byte
+
+	// templated is set if headers are templates, only then the request is read for template data.
+	templated bool
+}
+
+type pathRewrite struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// proxyDataKey is a context key of the template data of the proxied request.
+type proxyDataKey struct{}
+
+// newProxyRules compiles the rules, returns nil if none are configured.
+func newProxyRules(cfg *config.ProxyRules) (*proxyRules, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	rules := &proxyRules{}
+
+	if req := cfg.Request; req != nil {
+		headers, err := compileValues("proxy request header", req.SetHeaders)
+		if err != nil {
+			return nil, err
+		}
+
+		rules.setRequestHeaders = headers
+		rules.removeRequestHeaders = req.RemoveHeaders
+		rules.stripPrefix = req.StripPrefix
+
+		for _, rewrite := range req.Rewrite {
+			pattern, err := regexp.Compile(rewrite.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("compiling path rewrite [%s]: %w", rewrite.Pattern, err)
+			}
+
+			rules.rewrites = append(rules.rewrites, &pathRewrite{pattern: pattern, replacement: rewrite.Replacement})
+		}
+	}
+
+	if resp := cfg.Response; resp != nil {
+		headers, err := compileValues("proxy response header", resp.SetHeaders)
+		if err != nil {
+			return nil, err
+		}
+
+		rules.status = resp.Status
+		rules.setResponseHeaders = headers
+		rules.removeResponseHeaders = resp.RemoveHeaders
+		rules.jsonPatch = resp.JSONPatch
+
+		if resp.MergePatch != nil {
+			// Kept encoded to get a fresh copy for every response, as merging shares values of the patch.
+			rules.mergePatch, err = json.Marshal(resp.MergePatch)
+			if err != nil {
+				return nil, fmt.Errorf("encoding merge patch: %w", err)
+			}
+		}
+	}
+
+	rules.templated = templated(rules.setRequestHeaders) || templated(rules.setResponseHeaders)
+
+	return rules, nil
+}
+
+func (r *proxyRules) patchesBody() bool {
+	return len(r.jsonPatch) > 0 || r.mergePatch != nil
+}
+
+// rewriteRequest transforms the request before it is forwarded, returns the transformed copy.
+func (r *proxyRules) rewriteRequest(req *http.Request) *http.Request {
+	var data *templateData
+
+	if r.templated {
+		data = newTemplateData(req)
+		req = req.Clone(context.WithValue(req.Context(), proxyDataKey{}, data))
+	} else {
+		req = req.Clone(req.Context())
+	}
+
+	for _, name := range r.removeRequestHeaders {
+		req.Header.Del(name)
+	}
+
+	for name, value := range r.setRequestHeaders {
+		if name == "Host" {
+			req.Host = value.render(data)
+
+			continue
+		}
+
+		req.Header.Set(name, value.render(data))
+	}
+
+	if r.patchesBody() {
+		// Upstream responds with an encoding the transport decodes transparently, so that the body can be patched.
+		req.Header.Del("Accept-Encoding")
+	}
+
+	path := req.URL.Path
+	if r.stripPrefix != "" {
+		path = "/" + strings.TrimPrefix(strings.TrimPrefix(path, r.stripPrefix), "/")
+	}
+
+	for _, rewrite := range r.rewrites {
+		path = rewrite.pattern.ReplaceAllString(path, rewrite.replacement)
+	}
+
+	if path != req.URL.Path {
+		req.URL.Path = path
+		req.URL.RawPath = ""
+	}
+
+	return req
+}
+
+// modifyResponse transforms the upstream response.
+func (r *proxyRules) modifyResponse(resp *http.Response) error {
+	data, _ := resp.Request.Context().Value(proxyDataKey{}).(*templateData)
+
+	if r.status > 0 {
+		resp.StatusCode = r.status
+		resp.Status = fmt.Sprintf("%d %s", r.status, http.StatusText(r.status))
+	}
+
+	for _, name := range r.removeResponseHeaders {
+		resp.Header.Del(name)
+	}
+
+	for name, value := range r.setResponseHeaders {
+		resp.Header.Set(name, value.render(data))
+	}
+
+	if !r.patchesBody() || !isJSONResponse(resp) {
+		return nil
+	}
+
+	return r.patchBody(resp)
+}
+
+func (r *proxyRules) patchBody(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		return fmt.Errorf("reading upstream response: %w", err)
+	}
+
+	doc, err := decodeJSON(body)
+	if err != nil {
+		return err
+	}
+
+	doc, err = applyJSONPatch(doc, r.jsonPatch)
+	if err != nil {
+		return err
+	}
+
+	if r.mergePatch != nil {
+		patch, err := decodeJSON(r.mergePatch)
+		if err != nil {
+			return err
+		}
+
+		doc = applyMergePatch(doc, patch)
+	}
+
+	body, err = encodeJSON(doc)
+	if err != nil {
+		return err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	// Validators of the upstream body don't match the patched one.
+	resp.Header.Del("ETag")
+	resp.Header.Del("Content-MD5")
+
+	return nil
+}
+
+// isJSONResponse tells if the response has a JSON body, which isn't encoded.
+func isJSONResponse(resp *http.Response) bool {
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	return isJSONMediaType(mediaType)
+}
+
+// isJSONMediaType tells if the media type is JSON or its structured syntax suffix.
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == contentTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,94 @@
+package app_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyRules(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		writer.Header().Set("ETag", `"upstream"`)
+		writer.Header().Set("X-Internal", "secret")
+
+		_ = json.NewEncoder(writer).Encode(map[string]any{
+			"path":    req.URL.Path,
+			"tenant":  req.Header.Get("X-Tenant"),
+			"cookie":  req.Header.Get("Cookie"),
+			"status":  "active",
+			"items":   []any{map[string]any{"price": 10}},
+			"details": map[string]any{"a": 1, "b": 2},
+		})
+	}))
+	t.Cleanup(upstream.Close)
+
+	handler := newMockHandler(t, t.TempDir(), fmt.Sprintf(`{"endpoints": [{
+		"path": "/api/*",
+		"proxy": %q,
+		"proxyRules": {
+			"request": {
+				"setHeaders": {"X-Tenant": "{{.Query.tenant}}"},
+				"removeHeaders": ["Cookie"],
+				"stripPrefix": "/api",
+				"rewrite": [{"pattern": "^/v1/(.*)$", "replacement": "/v2/$1"}]
+			},
+			"response": {
+				"status": 202,
+				"setHeaders": {"X-Proxied-Path": "{{.Path}}"},
+				"removeHeaders": ["X-Internal"],
+				"jsonPatch": [
+					{"op": "replace", "path": "/status", "value": "suspended"},
+					{"op": "add", "path": "/items/0/discount", "value": 5}
+				],
+				"mergePatch": {"details": {"a": null, "c": 3}}
+			}
+		}
+	}]}`, upstream.URL))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users?tenant=acme", nil)
+	req.Header.Set("Cookie", "session=1")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "/api/v1/users", rec.Header().Get("X-Proxied-Path"))
+	assert.Empty(t, rec.Header().Get("X-Internal"))
+	assert.Empty(t, rec.Header().Get("ETag"))
+	assert.Equal(t, fmt.Sprint(rec.Body.Len()), rec.Header().Get("Content-Length"))
+	assert.JSONEq(t, `{
+		"path": "/v2/users",
+		"tenant": "acme",
+		"cookie": "",
+		"status": "suspended",
+		"items": [{"price": 10, "discount": 5}],
+		"details": {"b": 2, "c": 3}
+	}`, rec.Body.String())
+}
+
+func TestProxyRules_FailedPatch(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"status": "active"}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	handler := newMockHandler(t, t.TempDir(), fmt.Sprintf(`{"endpoints": [{
+		"path": "/users",
+		"proxy": %q,
+		"proxyRules": {"response": {"jsonPatch": [{"op": "test", "path": "/status", "value": "deleted"}]}}
+	}]}`, upstream.URL))
+
+	rec := serveWith(handler, "/users", nil)
+	require.Equal(t, http.StatusBadGateway, rec.Code)
+}
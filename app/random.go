@@ -0,0 +1,47 @@
+package app
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// random is a concurrency safe pseudo random generator, seeded for reproducible runs.
+type random struct {
+	rnd  *rand.Rand
+	lock sync.Mutex
+}
+
+// newRandom creates new random, stream separates sequences of different consumers sharing the seed.
+// Zero seed picks a random one.
+func newRandom(seed int64, stream uint64) *random {
+	if seed == 0 {
+		return &random{
+			rnd: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), //nolint:gosec // not used for security
+		}
+	}
+
+	return &random{
+		rnd: rand.New(rand.NewPCG(uint64(seed), stream)), //nolint:gosec // reproducible sequences are required
+	}
+}
+
+func (r *random) Float64() float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.rnd.Float64()
+}
+
+func (r *random) NormFloat64() float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.rnd.NormFloat64()
+}
+
+func (r *random) IntN(n int) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.rnd.IntN(n)
+}
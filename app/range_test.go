@@ -0,0 +1,101 @@
+package app_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRange_BodyFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.csv"), []byte("id,name\n1,foo\n2,bar\n"), 0o600))
+
+	handler := newMockHandler(t, dir, `{"endpoints": [
+		{"path": "/report", "bodyFile": "report.csv", "cache": {}},
+		{"path": "/created", "status": 201, "bodyFile": "report.csv"}
+	]}`)
+
+	rec := serveWith(handler, "/report", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+	assert.NotEmpty(t, rec.Header().Get("Last-Modified"))
+
+	etag := rec.Header().Get("ETag")
+
+	rec = serveWith(handler, "/report", map[string]string{"Range": "bytes=8-12"})
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "bytes 8-12/20", rec.Header().Get("Content-Range"))
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "1,foo", rec.Body.String())
+
+	// Resumes only if the representation is still the same.
+	rec = serveWith(handler, "/report", map[string]string{"Range": "bytes=14-", "If-Range": etag})
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "2,bar\n", rec.Body.String())
+
+	rec = serveWith(handler, "/report", map[string]string{"Range": "bytes=14-", "If-Range": `"stale"`})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "id,name\n1,foo\n2,bar\n", rec.Body.String())
+
+	rec = serveWith(handler, "/report", map[string]string{"Range": "bytes=100-"})
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, rec.Code)
+
+	// Ranges apply to 200 responses only.
+	rec = serveWith(handler, "/created", map[string]string{"Range": "bytes=0-1"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "id,name\n1,foo\n2,bar\n", rec.Body.String())
+}
+
+func TestRange_Generate(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"endpoints": [
+		{"path": "/random", "generate": {"size": "1MiB", "seed": 42, "name": "blob.bin"}},
+		{"path": "/sequence", "generate": {"size": "2KB", "pattern": "sequence"}},
+		{"path": "/huge", "generate": {"size": "2GiB"}}
+	]}`)
+
+	full := serveWith(handler, "/random", nil)
+	assert.Equal(t, http.StatusOK, full.Code)
+	assert.Equal(t, "application/octet-stream", full.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=blob.bin", full.Header().Get("Content-Disposition"))
+	assert.Equal(t, strconv.Itoa(1<<20), full.Header().Get("Content-Length"))
+	require.Len(t, full.Body.Bytes(), 1<<20)
+
+	// The same bytes every time and in every range.
+	assert.Equal(t, full.Body.Bytes(), serveWith(handler, "/random", nil).Body.Bytes())
+
+	rec := serveWith(handler, "/random", map[string]string{"Range": "bytes=1000-1999", "If-Range": full.Header().Get("ETag")})
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, full.Body.Bytes()[1000:2000], rec.Body.Bytes())
+
+	rec = serveWith(handler, "/sequence", map[string]string{"Range": "bytes=255-257"})
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, []byte{255, 0, 1}, rec.Body.Bytes())
+
+	rec = serveWith(handler, "/huge", map[string]string{"Range": "bytes=-16"})
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "bytes 2147483632-2147483647/2147483648", rec.Header().Get("Content-Range"))
+	assert.Len(t, rec.Body.Bytes(), 16)
+}
+
+func TestRange_GenerateInvalidSkipsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"endpoints": [
+		{"path": "/negative", "generate": {"size": "-1"}},
+		{"path": "/overflow", "generate": {"size": "9000000TiB"}},
+		{"path": "/pattern", "generate": {"size": "1KB", "pattern": "zeros"}}
+	]}`)
+
+	for _, path := range []string{"/negative", "/overflow", "/pattern"} {
+		assert.Equal(t, http.StatusNotFound, serveWith(handler, path, nil).Code, path)
+	}
+}
@@ -0,0 +1,292 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const kindReplay = "replay"
+
+// replay answers requests with the best matching responses of recorded traffic.
+type replay struct {
+	entries []*replayEntry
+	query   []string // compared query parameters, all of them if empty
+	headers []string
+	body    bool
+	ignore  []string
+	strict  bool
+	log     *slog.Logger
+	lock    sync.Mutex
+}
+
+type replayEntry struct {
+	*JournalEntry
+
+	body string // normalised request body
+	used int    // number of times the response was replayed, to cycle through responses of repeated requests
+}
+
+// newReplay loads the capture file of the replay, returns nil if replay is not configured.
+func newReplay(mockPath string, cfg *config.Replay, log *slog.Logger) (*replay, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	entries, err := LoadCapture(resolvePath(mockPath, cfg.File))
+	if err != nil {
+		return nil, err
+	}
+
+	rpl := &replay{
+		body:   true,
+		ignore: cfg.Ignore,
+		strict: cfg.Strict,
+		log:    log,
+	}
+
+	if cfg.Match != nil {
+		rpl.query = cfg.Match.Query
+		rpl.headers = cfg.Match.Headers
+		rpl.body = cfg.Match.Body
+	}
+
+	for _, entry := range entries {
+		// Cut off bodies would be matched or replayed as complete ones.
+		if entry.Request.Truncated || entry.Response.Truncated {
+			log.Warn("skipping recording with a truncated body", "method", entry.Request.Method,
+				"uri", entry.Request.URL)
+
+			continue
+		}
+
+		body := recordedBody(entry.Request.Body, entry.Request.BodyEncoding)
+
+		rpl.entries = append(rpl.entries, &replayEntry{
+			JournalEntry: entry,
+			body:         normaliseBody(body, entry.Request.Header.Get("Content-Type"), rpl.ignore),
+		})
+	}
+
+	log.Info(fmt.Sprintf("replaying %d recorded responses from %s", len(rpl.entries), cfg.File))
+
+	return rpl, nil
+}
+
+// unusableReplay answers every request with 501, so that a replay, which can't be loaded, fails loudly
+// instead of silently serving live endpoints and upstreams.
+func unusableReplay(err error) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		setAccessInfo(req, "", kindReplay)
+		http.Error(writer, fmt.Sprintf("replay is unusable: %v", err), http.StatusNotImplemented)
+	})
+}
+
+// Middleware returns the replay middleware handler, requests without recorded responses are passed
+// to the next handler or fail in strict mode.
+func (r *replay) Middleware(next http.Handler) http.Handler {
+	if r == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		// Service endpoints aren't recorded.
+		if req.URL.Path == "/healthcheck" || req.URL.Path == "/version" {
+			next.ServeHTTP(writer, req)
+
+			return
+		}
+
+		data, _ := peekBody(req)
+		body := normaliseBody(data, req.Header.Get("Content-Type"), r.ignore)
+
+		entry := r.find(req, body)
+
+		switch {
+		case entry != nil:
+			setAccessInfo(req, entry.Request.Path, kindReplay)
+			writeRecorded(writer, &entry.Response)
+		case r.strict:
+			r.log.Error("no recorded response matches the request", "method", req.Method, "uri", req.RequestURI)
+			setAccessInfo(req, "", kindReplay)
+			http.Error(writer, fmt.Sprintf("no recorded response matches %s %s", req.Method, req.RequestURI),
+				http.StatusNotImplemented)
+		default:
+			next.ServeHTTP(writer, req)
+		}
+	})
+}
+
+// find returns the entry with the same method and path, which matches most of the keys,
+// all of them in strict mode. Least replayed entries win ties.
+func (r *replay) find(req *http.Request, body string) *replayEntry {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var (
+		best      *replayEntry
+		bestScore int
+	)
+
+	query := req.URL.Query()
+
+	for _, entry := range r.entries {
+		if !strings.EqualFold(entry.Request.Method, req.Method) || entry.Request.Path != req.URL.Path {
+			continue
+		}
+
+		score, total := r.score(entry, query, req.Header, body)
+		if r.strict && score < total {
+			continue
+		}
+
+		if best == nil || score > bestScore || (score == bestScore && entry.used < best.used) {
+			best, bestScore = entry, score
+		}
+	}
+
+	if best != nil {
+		best.used++
+	}
+
+	return best
+}
+
+// score returns the number of keys of the request matching the entry and the number of compared keys.
+func (r *replay) score(entry *replayEntry, query url.Values, header http.Header, body string) (int, int) {
+	score, total := 0, 0
+
+	compare := func(equal bool) {
+		total++
+
+		if equal {
+			score++
+		}
+	}
+
+	for _, name := range r.queryNames(entry.Request.Query, query) {
+		compare(slices.Equal(entry.Request.Query[name], query[name]))
+	}
+
+	for _, name := range r.headers {
+		compare(entry.Request.Header.Get(name) == header.Get(name))
+	}
+
+	if r.body {
+		compare(entry.body == body)
+	}
+
+	return score, total
+}
+
+func (r *replay) queryNames(recorded, actual url.Values) []string {
+	names := r.query
+
+	if len(names) == 0 {
+		for name := range recorded {
+			names = append(names, name)
+		}
+
+		for name := range actual {
+			if _, ok := recorded[name]; !ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return slices.DeleteFunc(slices.Clone(names), func(name string) bool {
+		return slices.Contains(r.ignore, name)
+	})
+}
+
+// normaliseBody returns a canonical form of the body without ignored fields: JSON with sorted keys,
+// sorted form values or trimmed text.
+func normaliseBody(data []byte, contentType string, ignore []string) string {
+	if len(data) == 0 {
+		return ""
+	}
+
+	if doc, err := decodeJSON(data); err == nil {
+		for _, field := range ignore {
+			removeField(doc, strings.Split(field, "."))
+		}
+
+		normalised, err := json.Marshal(doc)
+		if err == nil {
+			return string(normalised)
+		}
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		if values, err := url.ParseQuery(string(data)); err == nil {
+			for _, field := range ignore {
+				values.Del(field)
+			}
+
+			return values.Encode()
+		}
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+// removeField removes the field by its path from the root, a single name is removed at any depth.
+// Arrays are traversed transparently.
+func removeField(value any, path []string) {
+	removeFieldAt(value, path, len(path) == 1)
+}
+
+func removeFieldAt(value any, path []string, anyDepth bool) {
+	switch node := value.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			delete(node, path[0])
+		} else if child, ok := node[path[0]]; ok {
+			removeFieldAt(child, path[1:], false)
+		}
+
+		if anyDepth {
+			for _, child := range node {
+				removeFieldAt(child, path, true)
+			}
+		}
+	case []any:
+		for _, child := range node {
+			removeFieldAt(child, path, anyDepth)
+		}
+	}
+}
+
+// writeRecorded writes the recorded response.
+func writeRecorded(writer http.ResponseWriter, resp *RecordedResponse) {
+	header := writer.Header()
+
+	for name, values := range resp.Header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Length", "Transfer-Encoding", "Connection":
+			continue
+		}
+
+		header[name] = slices.Clone(values)
+	}
+
+	body := recordedBody(resp.Body, resp.BodyEncoding)
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	writer.WriteHeader(status)
+	_, _ = writer.Write(body)
+}
@@ -0,0 +1,270 @@
+package app_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock/app"
+)
+
+const replayCapture = `{"version": 1, "entries": [
+	{"request": {"method": "GET", "url": "/users?page=1", "path": "/users", "query": {"page": ["1"]}},
+	 "response": {"status": 200, "headers": {"Content-Type": ["application/json"]}, "body": "[{\"id\":1}]"}},
+	{"request": {"method": "GET", "url": "/users?page=2", "path": "/users", "query": {"page": ["2"]}},
+	 "response": {"status": 200, "headers": {"Content-Type": ["application/json"]}, "body": "[{\"id\":2}]"}},
+	{"request": {"method": "POST", "url": "/orders", "path": "/orders",
+		"headers": {"Content-Type": ["application/json"]}, "body": "{\"item\":\"book\",\"nonce\":\"a1\"}"},
+	 "response": {"status": 201, "body": "created"}},
+	{"request": {"method": "GET", "url": "/tick", "path": "/tick"}, "response": {"status": 200, "body": "one"}},
+	{"request": {"method": "GET", "url": "/tick", "path": "/tick"}, "response": {"status": 200, "body": "two"}},
+	{"request": {"method": "GET", "url": "/logo", "path": "/logo"},
+	 "response": {"status": 200, "body": "iVBORw==", "bodyEncoding": "base64"}}
+]}`
+
+func newReplayHandler(t *testing.T, capture, replay string) http.Handler {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "capture.json"), []byte(capture), 0o600))
+
+	return newMockHandler(t, dir, fmt.Sprintf(`{"replay": %s, "endpoints": [
+		{"path": "/live", "json": {"live": true}}
+	]}`, replay))
+}
+
+func replayRequest(handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestReplay_Matching(t *testing.T) {
+	t.Parallel()
+
+	handler := newReplayHandler(t, replayCapture, `{"file": "capture.json", "ignore": ["nonce"], "strict": true}`)
+
+	rec := replayRequest(handler, http.MethodGet, "/users?page=2", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `[{"id":2}]`, rec.Body.String())
+
+	rec = replayRequest(handler, http.MethodPost, "/orders", `{"nonce": "b2", "item": "book"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "created", rec.Body.String())
+
+	rec = replayRequest(handler, http.MethodGet, "/logo", "")
+	assert.Equal(t, "\x89PNG", rec.Body.String())
+}
+
+func TestReplay_CyclesRepeatedRequests(t *testing.T) {
+	t.Parallel()
+
+	handler := newReplayHandler(t, replayCapture, `{"file": "capture.json"}`)
+
+	for _, want := range []string{"one", "two", "one"} {
+		assert.Equal(t, want, replayRequest(handler, http.MethodGet, "/tick", "").Body.String())
+	}
+}
+
+func TestReplay_Strict(t *testing.T) {
+	t.Parallel()
+
+	handler := newReplayHandler(t, replayCapture, `{"file": "capture.json", "strict": true}`)
+
+	for _, path := range []string{"/users?page=3", "/live"} {
+		rec := replayRequest(handler, http.MethodGet, path, "")
+		assert.Equal(t, http.StatusNotImplemented, rec.Code, path)
+		assert.Contains(t, rec.Body.String(), "no recorded response matches GET "+path)
+	}
+
+	rec := replayRequest(handler, http.MethodPost, "/orders", `{"item": "pen", "nonce": "a1"}`)
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestReplay_FallsThrough(t *testing.T) {
+	t.Parallel()
+
+	handler := newReplayHandler(t, replayCapture, `{"file": "capture.json"}`)
+
+	rec := replayRequest(handler, http.MethodGet, "/live", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"live": true}`, rec.Body.String())
+
+	// The closest recording is served, when nothing matches exactly.
+	rec = replayRequest(handler, http.MethodGet, "/users?page=3", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"id"`)
+}
+
+func TestReplay_Unusable(t *testing.T) {
+	t.Parallel()
+
+	for name, capture := range map[string]string{"corrupt": `{"entries": [`, "missing": ""} {
+		dir := t.TempDir()
+		if capture != "" {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "capture.json"), []byte(capture), 0o600))
+		}
+
+		handler := newMockHandler(t, dir, `{"replay": {"file": "capture.json"}, "endpoints": [
+			{"path": "/live", "json": {"live": true}}
+		]}`)
+
+		for _, path := range []string{"/live", "/healthcheck"} {
+			rec := replayRequest(handler, http.MethodGet, path, "")
+			assert.Equal(t, http.StatusNotImplemented, rec.Code, name)
+			assert.Contains(t, rec.Body.String(), "replay is unusable", name)
+		}
+	}
+}
+
+func TestReplay_SkipsTruncated(t *testing.T) {
+	t.Parallel()
+
+	handler := newReplayHandler(t, `{"version": 1, "entries": [
+		{"request": {"method": "GET", "url": "/big", "path": "/big"}, "response": {"status": 200, "body": "cut", "truncated": true}}
+	]}`, `{"file": "capture.json", "strict": true}`)
+
+	assert.Equal(t, http.StatusNotImplemented, replayRequest(handler, http.MethodGet, "/big", "").Code)
+}
+
+func TestReplay_HAR(t *testing.T) {
+	t.Parallel()
+
+	har := `{"log": {"version": "1.2", "creator": {"name": "test", "version": "1"}, "entries": [
+		{"startedDateTime": "2024-01-01T00:00:00Z", "time": 1,
+		 "request": {"method": "GET", "url": "https://api.example.com/users?page=1", "httpVersion": "HTTP/1.1",
+			"headers": [{"name": ":authority", "value": "api.example.com"}], "queryString": [], "cookies": [],
+			"headersSize": -1, "bodySize": 0},
+		 "response": {"status": 200, "statusText": "OK", "httpVersion": "HTTP/1.1", "cookies": [],
+			"headers": [{"name": "Content-Encoding", "value": "gzip"}],
+			"content": {"size": 10, "mimeType": "application/json", "text": "[{\"id\":1}]"},
+			"redirectURL": "", "headersSize": -1, "bodySize": -1},
+		 "cache": {}, "timings": {"send": 0, "wait": 1, "receive": 0}}
+	]}}`
+
+	handler := newReplayHandler(t, har, `{"file": "capture.json", "strict": true}`)
+
+	rec := replayRequest(handler, http.MethodGet, "/users?page=1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, `[{"id":1}]`, rec.Body.String())
+}
+
+func TestJournal_SaveAndLoad(t *testing.T) {
+	t.Parallel()
+
+	journal := app.NewJournal(0)
+	handler := journal.Middleware(newMockHandler(t, t.TempDir(), `{"endpoints": [
+		{"path": "/users", "json": [{"id": 1}]}
+	]}`))
+
+	replayRequest(handler, http.MethodGet, "/users?page=1", "")
+
+	file := filepath.Join(t.TempDir(), "capture.json")
+	require.NoError(t, journal.Save(file))
+
+	entries, err := app.LoadCapture(file)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "/users", entries[0].Request.Path)
+	assert.Equal(t, []string{"1"}, entries[0].Request.Query["page"])
+	assert.JSONEq(t, `[{"id": 1}]`, entries[0].Response.Body)
+}
+
+func TestJournal_BodyLimit(t *testing.T) {
+	t.Parallel()
+
+	mock := newMockHandler(t, t.TempDir(), `{"endpoints": [{"path": "/echo", "json": {"text": "0123456789"}}]}`)
+
+	limited, whole := app.NewJournal(0), app.NewJournal(0)
+	limited.SetBodyLimit(8)
+	whole.SetBodyLimit(0)
+
+	replayRequest(limited.Middleware(mock), http.MethodGet, "/echo", `{"text": "0123456789"}`)
+	replayRequest(whole.Middleware(mock), http.MethodGet, "/echo", `{"text": "0123456789"}`)
+
+	entries := limited.Entries()
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].Request.Truncated)
+	assert.True(t, entries[0].Response.Truncated)
+	assert.Len(t, entries[0].Response.Body, 8)
+
+	entries = whole.Entries()
+	require.Len(t, entries, 1)
+	assert.False(t, entries[0].Request.Truncated)
+	assert.False(t, entries[0].Response.Truncated)
+	assert.JSONEq(t, `{"text": "0123456789"}`, entries[0].Response.Body)
+}
+
+func TestJournal_BodyLimitPassesWholeBody(t *testing.T) {
+	t.Parallel()
+
+	journal := app.NewJournal(0)
+	journal.SetBodyLimit(4)
+
+	echo := http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		_, _ = io.Copy(writer, req.Body)
+	})
+
+	rec := replayRequest(journal.Middleware(echo), http.MethodPost, "/echo", "0123456789")
+	assert.Equal(t, "0123456789", rec.Body.String())
+
+	entries := journal.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "0123", entries[0].Request.Body)
+	assert.True(t, entries[0].Request.Truncated)
+}
+
+func TestJournal_BuiltinAndPreflightMatched(t *testing.T) {
+	t.Parallel()
+
+	journal := app.NewJournal(0)
+	handler := journal.Middleware(newMockHandler(t, t.TempDir(), `{"endpoints": [
+		{"path": "/users/{id}", "json": {}, "allowCors": ["*"]}
+	]}`))
+
+	replayRequest(handler, http.MethodGet, "/healthcheck", "")
+	replayRequest(handler, http.MethodGet, "/version", "")
+
+	req := httptest.NewRequest(http.MethodOptions, "/users/1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := journal.Entries()
+	require.Len(t, entries, 3)
+
+	for idx, want := range []struct{ endpoint, kind string }{
+		{"/healthcheck", "builtin"}, {"/version", "builtin"}, {"/users/{id}", "preflight"},
+	} {
+		assert.True(t, entries[idx].Matched(), want.endpoint)
+		assert.Equal(t, want.endpoint, entries[idx].Endpoint)
+		assert.Equal(t, want.kind, entries[idx].Kind)
+	}
+}
+
+func TestLoadCapture_Unknown(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "capture.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"foo": []}`), 0o600))
+
+	_, err := app.LoadCapture(file)
+	assert.Error(t, err)
+}
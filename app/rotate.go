@@ -0,0 +1,107 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const logFilePerm = 0o600
+
+// rotatingFile is a size based rotating log file, it is not safe for concurrent use.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func openLogFile(path string) (*os.File, int64, error) {
+	file, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, logFilePerm)
+	if err != nil {
+		return nil, 0, fmt.Errorf("opening log file %s: %w", path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return nil, 0, fmt.Errorf("reading log file info %s: %w", path, err)
+	}
+
+	return file, info.Size(), nil
+}
+
+func newRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	file, size, err := openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		file:       file,
+		size:       size,
+	}, nil
+}
+
+func (r *rotatingFile) Write(data []byte) (int, error) {
+	if r.size > 0 && r.size+int64(len(data)) > r.maxSize {
+		err := r.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(data)
+	r.size += int64(n)
+
+	if err != nil {
+		return n, fmt.Errorf("writing log file %s: %w", r.path, err)
+	}
+
+	return n, nil
+}
+
+func (r *rotatingFile) Close() error {
+	err := r.file.Close()
+	if err != nil {
+		return fmt.Errorf("closing log file %s: %w", r.path, err)
+	}
+
+	return nil
+}
+
+func (r *rotatingFile) rotate() error {
+	err := r.Close()
+	if err != nil {
+		return err
+	}
+
+	if r.maxBackups > 0 {
+		for idx := r.maxBackups - 1; idx > 0; idx-- {
+			// Missing backups are expected until the log has rotated maxBackups times.
+			_ = os.Rename(r.backupName(idx), r.backupName(idx+1))
+		}
+
+		err = os.Rename(r.path, r.backupName(1))
+	} else {
+		err = os.Remove(r.path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("rotating log file %s: %w", r.path, err)
+	}
+
+	r.file, r.size, err = openLogFile(r.path)
+
+	return err
+}
+
+func (r *rotatingFile) backupName(idx int) string {
+	return r.path + "." + strconv.Itoa(idx)
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"bytes"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	soapVersion12 = "1.2"
+
+	contentTypeSOAP11 = "text/xml; charset=utf-8"
+	contentTypeSOAP12 = "application/soap+xml; charset=utf-8"
+)
+
+// soapFaultBody renders SOAP fault envelope of the given SOAP version.
+func soapFaultBody(fault *config.SOAPFault) *responseBody {
+	var buf bytes.Buffer
+
+	buf.WriteString(xml.Header)
+
+	if fault.Version == soapVersion12 {
+		buf.WriteString(`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><env:Fault>`)
+		buf.WriteString(`<env:Code><env:Value>`)
+		writeXMLText(&buf, soapFaultCode("env", fault.Code, "Receiver"))
+		buf.WriteString(`</env:Value></env:Code><env:Reason><env:Text xml:lang="en">`)
+		writeXMLText(&buf, fault.String)
+		buf.WriteString(`</env:Text></env:Reason>`)
+		writeXMLElement(&buf, "env:Role", fault.Actor, false)
+		writeXMLElement(&buf, "env:Detail", fault.Detail, true)
+		buf.WriteString(`</env:Fault></env:Body></env:Envelope>`)
+
+		return &responseBody{data: buf.Bytes(), contentType: contentTypeSOAP12}
+	}
+
+	buf.WriteString(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault>`)
+	writeXMLElement(&buf, "faultcode", soapFaultCode("soap", fault.Code, "Server"), false)
+	writeXMLElement(&buf, "faultstring", fault.String, false)
+	writeXMLElement(&buf, "faultactor", fault.Actor, false)
+	writeXMLElement(&buf, "detail", fault.Detail, true)
+	buf.WriteString(`</soap:Fault></soap:Body></soap:Envelope>`)
+
+	return &responseBody{data: buf.Bytes(), contentType: contentTypeSOAP11}
+}
+
+func soapFaultCode(prefix, code, fallback string) string {
+	if code == "" {
+		code = fallback
+	}
+
+	if strings.Contains(code, ":") {
+		return code
+	}
+
+	return prefix + ":" + code
+}
+
+// writeXMLElement writes element with escaped text or raw XML content, skips empty content.
+func writeXMLElement(buf *bytes.Buffer, name, content string, raw bool) {
+	if content == "" {
+		return
+	}
+
+	buf.WriteString("<" + name + ">")
+
+	if raw {
+		buf.WriteString(content)
+	} else {
+		writeXMLText(buf, content)
+	}
+
+	buf.WriteString("</" + name + ">")
+}
+
+func writeXMLText(buf *bytes.Buffer, text string) {
+	_ = xml.EscapeText(buf, []byte(text))
+}
+
+// soapAction returns SOAPAction header (SOAP 1.1) or the "action" parameter of the Content-Type (SOAP 1.2).
+func soapAction(req *http.Request) string {
+	if action := req.Header.Get("SOAPAction"); action != "" {
+		return strings.Trim(action, `"`)
+	}
+
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+
+	return params["action"]
+}
@@ -0,0 +1,80 @@
+package app_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/smeshkov/gomock/app"
+	"github.com/smeshkov/gomock/config"
+)
+
+func TestSOAP_RoutesOperationsOfOnePath(t *testing.T) {
+	t.Parallel()
+
+	handler := app.RegisterHandlers("test", ".", &config.Config{}, &config.Mock{
+		Endpoints: []*config.Endpoint{
+			{
+				Methods: []string{http.MethodPost},
+				Path:    "/soap",
+				Match:   &config.Match{SOAPAction: "urn:GetUser", XPath: map[string]string{"//GetUser/id": "1"}},
+				Body:    `<user><id>{{.XPath "//GetUser/id"}}</id></user>`,
+				Headers: map[string]string{"Content-Type": "text/xml"},
+			},
+			{
+				Methods:   []string{http.MethodPost},
+				Path:      "/soap",
+				Match:     &config.Match{SOAPAction: "urn:GetUser"},
+				SOAPFault: &config.SOAPFault{Code: "Client", String: "user <unknown>", Detail: "<code>404</code>"},
+			},
+			{
+				Methods:   []string{http.MethodPost},
+				Path:      "/soap",
+				Match:     &config.Match{Headers: map[string]string{"Content-Type": "application/soap+xml; action=urn:Pay"}},
+				SOAPFault: &config.SOAPFault{Version: "1.2", String: "declined"},
+			},
+		},
+	})
+
+	serve := func(action, contentType, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(
+			`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>`+
+				`<GetUser><id>`+id+`</id></GetUser></soap:Body></soap:Envelope>`))
+		req.Header.Set("Content-Type", contentType)
+
+		if action != "" {
+			req.Header.Set("SOAPAction", `"`+action+`"`)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := serve("urn:GetUser", "text/xml", "1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "<user><id>1</id></user>", rec.Body.String())
+
+	rec = serve("urn:GetUser", "text/xml", "2")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "text/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(),
+		"<faultcode>soap:Client</faultcode><faultstring>user &lt;unknown&gt;</faultstring><detail><code>404</code></detail>")
+
+	rec = serve("", "application/soap+xml; action=urn:Pay", "1")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "application/soap+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "<env:Value>env:Receiver</env:Value>")
+
+	rec = serve("urn:Unknown", "text/xml", "1")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/soap", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "POST", rec.Header().Get("Allow"))
+}
@@ -35,6 +35,30 @@ func (s *store) Write(entity, key string, value any) {
 	s.table[entity] = table
 }
 
+// Update replaces the value of the key with the result of the function, which gets the current value,
+// nothing is written if the function fails.
+func (s *store) Update(entity, key string, update func(current any, found bool) (any, error)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	table, isMap := s.table[entity].(map[string]any)
+	if !isMap {
+		table = map[string]any{}
+	}
+
+	current, found := table[key]
+
+	value, err := update(current, found)
+	if err != nil {
+		return err
+	}
+
+	table[key] = value
+	s.table[entity] = table
+
+	return nil
+}
+
 func (s *store) Read(entity, key string) (any, bool) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
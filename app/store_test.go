@@ -1,6 +1,7 @@
 package app //nolint:testpackage // testing unexported store internals
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -32,3 +33,29 @@ func Test_ReadAll(t *testing.T) {
 	assert.Contains(t, table, "bar2")
 	assert.Contains(t, table, "bar3")
 }
+
+func Test_Update(t *testing.T) {
+	t.Parallel()
+
+	store := newStore()
+	store.Write("foo", "bar", "bee")
+
+	err := store.Update("foo", "bar", func(current any, found bool) (any, error) {
+		assert.True(t, found)
+		assert.Equal(t, "bee", current)
+
+		return "bee2", nil
+	})
+	assert.NoError(t, err)
+
+	errFailed := errors.New("failed")
+
+	err = store.Update("foo", "bar", func(any, bool) (any, error) {
+		return "bee3", errFailed
+	})
+	assert.Equal(t, errFailed, err)
+
+	val, ok := store.Read("foo", "bar")
+	assert.True(t, ok)
+	assert.Equal(t, "bee2", val)
+}
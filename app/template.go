@@ -0,0 +1,160 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// templateData is available to response templates, e.g. {{.Params.id}} or {{.Query.page}}.
+type templateData struct {
+	Method string
+	Path   string
+	Params map[string]string // URL parameters of the route
+	Query  map[string]string // first values of query parameters
+	Header map[string]string // first values of request headers, by canonical name
+	Form   map[string]string // first values of urlencoded or multipart form fields
+
+	Principal string         // authenticated principal, see "auth"
+	Claims    map[string]any // claims of the verified bearer JWT, see "auth.jwt"
+
+	body []byte
+	doc  *xmlNode
+}
+
+var templateFuncs = template.FuncMap{
+	"now": time.Now,
+	"add": func(value any, delta int) (int, error) {
+		num, err := strconv.Atoi(strings.TrimSpace(fmt.Sprint(value)))
+		if err != nil {
+			return 0, fmt.Errorf("adding to a non number [%v]: %w", value, err)
+		}
+
+		return num + delta, nil
+	},
+	"default": func(fallback, value any) any {
+		if value == nil || fmt.Sprint(value) == "" {
+			return fallback
+		}
+
+		return value
+	},
+}
+
+func newTemplateData(req *http.Request) *templateData {
+	data := &templateData{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Params: map[string]string{},
+		Query:  map[string]string{},
+		Header: map[string]string{},
+		Form:   map[string]string{},
+
+		Principal: principalOf(req),
+		Claims:    claimsOf(req),
+	}
+
+	if routeCtx := chi.RouteContext(req.Context()); routeCtx != nil {
+		for idx, key := range routeCtx.URLParams.Keys {
+			data.Params[key] = routeCtx.URLParams.Values[idx]
+		}
+	}
+
+	for key, values := range req.URL.Query() {
+		data.Query[key] = values[0]
+	}
+
+	for key, values := range req.Header {
+		data.Header[key] = values[0]
+	}
+
+	data.body, _ = peekBody(req)
+
+	if form, err := readRequestForm(req); err == nil && form != nil {
+		for key, values := range form.values {
+			data.Form[key] = values[0]
+		}
+
+		form.close()
+	}
+
+	return data
+}
+
+// XPath returns the first value selected by the expression in the XML body of the request,
+// e.g. {{.XPath "/Envelope/Body/GetUser/id"}}.
+func (d *templateData) XPath(expr string) (string, error) {
+	compiled, err := compileXPath(expr)
+	if err != nil {
+		return "", err
+	}
+
+	if d.doc == nil {
+		d.doc, err = parseXML(d.body)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	values := compiled.eval(d.doc)
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	return values[0], nil
+}
+
+// valueTemplate is a string which is rendered as a template only if it contains actions.
+type valueTemplate struct {
+	raw  string
+	tmpl *template.Template
+}
+
+func newValueTemplate(name, raw string) (*valueTemplate, error) {
+	value := &valueTemplate{raw: raw}
+
+	if !strings.Contains(raw, "{{") {
+		return value, nil
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template [%s]: %w", name, err)
+	}
+
+	value.tmpl = tmpl
+
+	return value, nil
+}
+
+// templated reports if any of the values is a template.
+func templated(values map[string]*valueTemplate) bool {
+	for _, value := range values {
+		if value.tmpl != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// render renders the template, falling back to the raw string on errors.
+func (v *valueTemplate) render(data *templateData) string {
+	if v.tmpl == nil {
+		return v.raw
+	}
+
+	var buf strings.Builder
+
+	err := v.tmpl.Execute(&buf, data)
+	if err != nil {
+		return v.raw
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	defaultChunkSize = 1024
+	chunksPerSecond  = 10
+)
+
+// throttle limits throughput of response bodies by sending them in delayed fragments.
+type throttle struct {
+	chunkSize  int
+	chunkDelay time.Duration
+	perByte    time.Duration
+}
+
+// newThrottle resolves throttle of the endpoint, falling back to the global one, returns nil if not configured.
+func newThrottle(endpoint, global *config.Throttle) *throttle {
+	cfg := endpoint
+	if cfg == nil {
+		cfg = global
+	}
+
+	if cfg == nil || (cfg.BytesPerSecond <= 0 && cfg.ChunkDelay <= 0) {
+		return nil
+	}
+
+	thr := &throttle{
+		chunkSize:  cfg.ChunkSize,
+		chunkDelay: time.Duration(cfg.ChunkDelay) * time.Millisecond,
+	}
+
+	if cfg.BytesPerSecond > 0 {
+		thr.perByte = time.Second / time.Duration(cfg.BytesPerSecond)
+
+		if thr.chunkSize <= 0 {
+			thr.chunkSize = max(cfg.BytesPerSecond/chunksPerSecond, 1)
+		}
+	}
+
+	if thr.chunkSize <= 0 {
+		thr.chunkSize = defaultChunkSize
+	}
+
+	return thr
+}
+
+// Middleware returns the throttling middleware handler.
+func (t *throttle) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(&throttledWriter{
+			ResponseWriter: writer,
+			throttle:       t,
+			ctx:            req.Context(),
+		}, req)
+	})
+}
+
+// throttledWriter writes and flushes the body fragment by fragment, pausing between them.
+type throttledWriter struct {
+	http.ResponseWriter
+
+	throttle *throttle
+	ctx      context.Context //nolint:containedctx // bound to a single request
+	pending  time.Duration
+}
+
+func (w *throttledWriter) Write(data []byte) (int, error) {
+	written := 0
+
+	for written < len(data) {
+		if w.pending > 0 {
+			timer := time.NewTimer(w.pending)
+
+			select {
+			case <-timer.C:
+			case <-w.ctx.Done():
+				timer.Stop()
+
+				return written, w.ctx.Err() //nolint:wrapcheck // request is cancelled
+			}
+		}
+
+		chunk := data[written:min(written+w.throttle.chunkSize, len(data))]
+
+		n, err := w.ResponseWriter.Write(chunk)
+		written += n
+
+		if err != nil {
+			return written, err //nolint:wrapcheck // transparent wrapper
+		}
+
+		_ = http.NewResponseController(w.ResponseWriter).Flush()
+
+		w.pending = w.throttle.chunkDelay + time.Duration(n)*w.throttle.perByte
+	}
+
+	return written, nil
+}
+
+func (w *throttledWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
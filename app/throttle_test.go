@@ -0,0 +1,57 @@
+package app_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock/app"
+	"github.com/smeshkov/gomock/config"
+)
+
+func TestThrottle_SlowDrip(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	payload := strings.Repeat("x", 100)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "files"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "files", "file.txt"), []byte(payload), 0o600))
+
+	handler := app.RegisterHandlers("test", ".", &config.Config{}, &config.Mock{
+		Throttle: &config.Throttle{ChunkSize: 25, ChunkDelay: 30},
+		Endpoints: []*config.Endpoint{
+			{Methods: []string{http.MethodGet}, Path: "/json", JSON: payload},
+			{Path: "/files/*", Static: dir},
+			{
+				Methods:  []string{http.MethodGet},
+				Path:     "/fast",
+				JSON:     payload,
+				Throttle: &config.Throttle{BytesPerSecond: 1 << 20},
+			},
+		},
+	})
+
+	for _, path := range []string{"/json", "/files/file.txt"} {
+		start := time.Now()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		body, err := io.ReadAll(rec.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), payload, path)
+		assert.GreaterOrEqual(t, int64(time.Since(start)), int64(90*time.Millisecond), path)
+	}
+
+	start := time.Now()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Less(t, int64(time.Since(start)), int64(50*time.Millisecond))
+}
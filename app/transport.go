@@ -0,0 +1,187 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	http2Off = "off"
+	http2H2C = "h2c"
+
+	outboundDirect = "direct"
+
+	defaultDialTimeout = 30 * time.Second
+	dialKeepAlive      = 30 * time.Second
+)
+
+var (
+	errInvalidCACert     = errors.New("no certificates found in CA file")
+	errClientKeyMissing  = errors.New("clientCert and clientKey must be set together")
+	errUnsupportedHTTP2  = errors.New("unsupported http2 mode")
+	errParseOutboundURL  = errors.New("error in parsing outbound proxy URL")
+	errParseProxyTimeout = errors.New("error in parsing proxy timeout")
+)
+
+// newProxyTransport creates a transport of requests to proxy upstreams, the endpoint options take precedence
+// over the global ones. Returns nil if none are configured, i.e. the default transport is used.
+func newProxyTransport(mockPath string, endpoint, global *config.ProxyOptions) (http.RoundTripper, error) {
+	cfg := endpoint
+	if cfg == nil {
+		cfg = global
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	// The default transport can be replaced by other packages, e.g. in tests.
+	transport := &http.Transport{}
+	if defaultTransport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = defaultTransport.Clone()
+	}
+
+	tlsConfig, err := newProxyTLSConfig(mockPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	err = setProxyTimeouts(transport, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+
+	switch cfg.HTTP2 {
+	case "":
+	case http2Off:
+		transport.Protocols = &http.Protocols{}
+		transport.Protocols.SetHTTP1(true)
+	case http2H2C:
+		transport.Protocols = &http.Protocols{}
+		transport.Protocols.SetHTTP2(true)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+	default:
+		return nil, fmt.Errorf("%w [%s]", errUnsupportedHTTP2, cfg.HTTP2)
+	}
+
+	switch cfg.OutboundProxy {
+	case "":
+	case outboundDirect:
+		transport.Proxy = nil
+	default:
+		outbound, err := url.Parse(cfg.OutboundProxy)
+		if err != nil || outbound.Host == "" {
+			return nil, fmt.Errorf("%w [%s]", errParseOutboundURL, cfg.OutboundProxy)
+		}
+
+		transport.Proxy = http.ProxyURL(outbound)
+	}
+
+	return transport, nil
+}
+
+func newProxyTLSConfig(mockPath string, cfg *config.ProxyOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify, //nolint:gosec // opted in for self-signed staging hosts
+		ServerName:         cfg.ServerName,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(filepath.Clean(resolvePath(mockPath, cfg.CACert)))
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w [%s]", errInvalidCACert, cfg.CACert)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return nil, errClientKeyMissing
+	}
+
+	if cfg.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(resolvePath(mockPath, cfg.ClientCert), resolvePath(mockPath, cfg.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func setProxyTimeouts(transport *http.Transport, cfg *config.ProxyOptions) error {
+	dialTimeout, err := parseProxyTimeout(cfg.DialTimeout, defaultDialTimeout)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: dialKeepAlive}
+	transport.DialContext = dialer.DialContext
+
+	transport.TLSHandshakeTimeout, err = parseProxyTimeout(cfg.TLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+	if err != nil {
+		return err
+	}
+
+	transport.ResponseHeaderTimeout, err = parseProxyTimeout(cfg.ResponseHeaderTimeout, transport.ResponseHeaderTimeout)
+	if err != nil {
+		return err
+	}
+
+	transport.IdleConnTimeout, err = parseProxyTimeout(cfg.IdleConnTimeout, transport.IdleConnTimeout)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func parseProxyTimeout(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w [%s]: %w", errParseProxyTimeout, value, err)
+	}
+
+	return timeout, nil
+}
+
+// resolvePath resolves the path relative to the mock file directory, absolute paths are kept.
+func resolvePath(mockPath, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(mockPath, path)
+}
@@ -0,0 +1,105 @@
+package app_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePEM(t *testing.T, dir, name, blockType string, data []byte) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}),
+		0o600))
+}
+
+func TestProxyOptions_TLS(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		_, _ = fmt.Fprintf(writer, "client certificates: %d", len(req.TLS.PeerCertificates))
+	}))
+	upstream.TLS = &tls.Config{ClientAuth: tls.RequestClientCert, MinVersion: tls.VersionTLS12}
+	upstream.StartTLS()
+	t.Cleanup(upstream.Close)
+
+	dir := t.TempDir()
+	cert := upstream.TLS.Certificates[0]
+
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	require.NoError(t, err)
+
+	writePEM(t, dir, "ca.pem", "CERTIFICATE", upstream.Certificate().Raw)
+	writePEM(t, dir, "client.pem", "CERTIFICATE", cert.Certificate[0])
+	writePEM(t, dir, "client.key", "PRIVATE KEY", key)
+
+	handler := newMockHandler(t, dir, fmt.Sprintf(`{
+		"proxyOptions": {"caCert": "ca.pem", "responseHeaderTimeout": "5s"},
+		"endpoints": [
+			{"path": "/default", "proxy": %[1]q},
+			{"path": "/mtls", "proxy": %[1]q, "proxyOptions": {
+				"caCert": "ca.pem", "clientCert": "client.pem", "clientKey": "client.key", "http2": "off"
+			}},
+			{"path": "/insecure", "proxy": %[1]q, "proxyOptions": {"insecureSkipVerify": true}},
+			{"path": "/untrusted", "proxy": %[1]q, "proxyOptions": {"dialTimeout": "1s"}}
+		]
+	}`, upstream.URL))
+
+	rec := serveWith(handler, "/default", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "client certificates: 0", rec.Body.String())
+
+	rec = serveWith(handler, "/mtls", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "client certificates: 1", rec.Body.String())
+
+	rec = serveWith(handler, "/insecure", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serveWith(handler, "/untrusted", nil)
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+}
+
+func TestProxyOptions_H2C(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		_, _ = writer.Write([]byte(req.Proto))
+	}))
+	upstream.Config.Protocols = &http.Protocols{}
+	upstream.Config.Protocols.SetHTTP1(true)
+	upstream.Config.Protocols.SetUnencryptedHTTP2(true)
+	upstream.Start()
+	t.Cleanup(upstream.Close)
+
+	handler := newMockHandler(t, t.TempDir(), fmt.Sprintf(`{"endpoints": [
+		{"path": "/h1", "proxy": %[1]q},
+		{"path": "/h2c", "proxy": %[1]q, "proxyOptions": {"http2": "h2c"}}
+	]}`, upstream.URL))
+
+	assert.Equal(t, "HTTP/1.1", serveWith(handler, "/h1", nil).Body.String())
+	assert.Equal(t, "HTTP/2.0", serveWith(handler, "/h2c", nil).Body.String())
+}
+
+func TestProxyOptions_Invalid(t *testing.T) {
+	t.Parallel()
+
+	handler := newMockHandler(t, t.TempDir(), `{"endpoints": [
+		{"path": "/timeout", "proxy": "http://localhost", "proxyOptions": {"dialTimeout": "soon"}},
+		{"path": "/key", "proxy": "http://localhost", "proxyOptions": {"clientCert": "client.pem"}},
+		{"path": "/http2", "proxy": "http://localhost", "proxyOptions": {"http2": "always"}}
+	]}`)
+
+	for _, path := range []string{"/timeout", "/key", "/http2"} {
+		assert.Equal(t, http.StatusNotFound, serveWith(handler, path, nil).Code, path)
+	}
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	uploadDirPerm  = 0o750
+	uploadFilePerm = 0o600
+)
+
+var (
+	errUploadNoFiles   = errors.New("no files in the request")
+	errUploadManyFiles = errors.New("more than one file for the key")
+)
+
+// storedFile is an uploaded file kept in the store, its content is either in memory or on disk.
+type storedFile struct {
+	Name        string    `json:"name"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	Path        string    `json:"path,omitempty"`
+	Modified    time.Time `json:"modified"`
+
+	data []byte
+}
+
+// content returns content of the file from memory or disk.
+func (f *storedFile) content() ([]byte, error) {
+	if f.Path == "" {
+		return f.data, nil
+	}
+
+	data, err := os.ReadFile(filepath.Clean(f.Path))
+	if err != nil {
+		return nil, fmt.Errorf("reading uploaded file %s: %w", f.Path, err)
+	}
+
+	return data, nil
+}
+
+// saveUploads stores uploaded files of the form by their key in the entity of the given name,
+// files are saved in the dir if it is set.
+func saveUploads(endpoint *config.Endpoint, name, dir string, form *requestForm, fields map[string]any,
+	database *store) ([]string, error) {
+	cfg := endpoint.Dynamic.Write.Files
+
+	headers := form.files(cfg.Field)
+	if len(headers) == 0 {
+		return nil, errUploadNoFiles
+	}
+
+	// Files of the request would overwrite each other under the same key.
+	if cfg.Key != "" && len(headers) > 1 {
+		return nil, fmt.Errorf("%w %s: got %d", errUploadManyFiles, cfg.Key, len(headers))
+	}
+
+	var keys []string
+
+	for _, header := range headers {
+		key := header.Filename
+
+		if cfg.Key != "" {
+			var err error
+
+			key, err = findKeyInJSON(cfg.Key, fields)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		file, err := readUpload(header, key, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		database.Write(name, key, file)
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func readUpload(header *multipart.FileHeader, key, dir string) (*storedFile, error) {
+	src, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening uploaded file %s: %w", header.Filename, err)
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("reading uploaded file %s: %w", header.Filename, err)
+	}
+
+	file := &storedFile{
+		Name:        filepath.Base(header.Filename),
+		ContentType: header.Header.Get("Content-Type"),
+		Size:        int64(len(data)),
+		Modified:    time.Now(),
+	}
+
+	// Generic types say nothing about the file, infer them from its name and content instead.
+	if file.ContentType == "" || file.ContentType == contentTypeBytes {
+		file.ContentType = contentTypeOf(header.Filename, data)
+	}
+
+	if dir == "" {
+		file.data = data
+
+		return file, nil
+	}
+
+	err = os.MkdirAll(dir, uploadDirPerm)
+	if err != nil {
+		return nil, fmt.Errorf("creating upload directory %s: %w", dir, err)
+	}
+
+	// Keys come from the client and may have path elements, e.g. "a/x.png" and "b/x.png",
+	// so the file is named by a hash of the whole key and keeps the extension of the uploaded file.
+	digest := sha256.Sum256([]byte(key))
+	file.Path = filepath.Join(dir, hex.EncodeToString(digest[:])+filepath.Ext(file.Name))
+
+	err = os.WriteFile(file.Path, data, uploadFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("saving uploaded file %s: %w", file.Path, err)
+	}
+
+	return file, nil
+}
+
+func contentDisposition(name string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": name})
+}
@@ -0,0 +1,247 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	strategyRoundRobin = "roundRobin"
+	strategyRandom     = "random"
+	strategyWeighted   = "weighted"
+
+	defaultMaxFails    = 1
+	defaultFailTimeout = 10 * time.Second
+)
+
+var (
+	errUpstreamStatus      = errors.New("upstream responded with a failover status")
+	errNoUpstreamTargets   = errors.New("no upstream targets")
+	errUnsupportedStrategy = errors.New("unsupported upstream strategy")
+)
+
+// upstreamPool proxies requests to one of the targets and fails over to the others.
+type upstreamPool struct {
+	targets  []*upstream
+	strategy string
+	statuses []int
+	maxFails int
+	timeout  time.Duration
+	fallback bool
+	rnd      *random
+	log      *slog.Logger
+	lock     sync.Mutex
+	next     int // next target of round robin
+}
+
+// upstream is a target of the pool with its passive health state.
+type upstream struct {
+	proxy     *Proxy
+	weight    int
+	current   int // current weight of smooth weighted round robin
+	fails     int
+	downUntil time.Time
+}
+
+// upstreamAttempt is a single attempt to proxy a request, failed attempts aren't written to the client.
+type upstreamAttempt struct {
+	statuses []int
+	last     bool // the last attempt passes failover statuses through, unless the pool falls back to the mock
+	err      error
+}
+
+type upstreamAttemptKey struct{}
+
+func upstreamAttemptOf(req *http.Request) *upstreamAttempt {
+	attempt, _ := req.Context().Value(upstreamAttemptKey{}).(*upstreamAttempt)
+
+	return attempt
+}
+
+func (a *upstreamAttempt) failsOn(status int) bool {
+	if a.last {
+		return false
+	}
+
+	if len(a.statuses) == 0 {
+		return status >= http.StatusInternalServerError
+	}
+
+	return slices.Contains(a.statuses, status)
+}
+
+func newUpstreamPool(serverAddr string, endpoint *config.Endpoint, transport http.RoundTripper, chaos *proxyChaos,
+	rnd *random, log *slog.Logger) (*upstreamPool, error) {
+	cfg := endpoint.Upstreams
+
+	if len(cfg.Targets) == 0 {
+		return nil, errNoUpstreamTargets
+	}
+
+	pool := &upstreamPool{
+		strategy: cfg.Strategy,
+		statuses: cfg.FailoverStatuses,
+		maxFails: cfg.MaxFails,
+		timeout:  defaultFailTimeout,
+		fallback: cfg.Fallback,
+		rnd:      rnd,
+		log:      log,
+	}
+
+	switch pool.strategy {
+	case "":
+		pool.strategy = strategyRoundRobin
+	case strategyRoundRobin, strategyRandom, strategyWeighted:
+	default:
+		return nil, fmt.Errorf("%w [%s]", errUnsupportedStrategy, pool.strategy)
+	}
+
+	if pool.maxFails <= 0 {
+		pool.maxFails = defaultMaxFails
+	}
+
+	if cfg.FailTimeout != "" {
+		timeout, err := time.ParseDuration(cfg.FailTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("parsing fail timeout [%s]: %w", cfg.FailTimeout, err)
+		}
+
+		pool.timeout = timeout
+	}
+
+	for _, target := range cfg.Targets {
+		proxy, err := newProxy(serverAddr, target.URL, endpoint.ProxyRules, transport, log)
+		if err != nil {
+			return nil, err
+		}
+
+		proxy.chaos = chaos
+		pool.targets = append(pool.targets, &upstream{proxy: proxy, weight: max(target.Weight, 1)})
+	}
+
+	return pool, nil
+}
+
+// ServeHTTP proxies the request to the selected target and to the next healthy ones on failures.
+// Returns false if every target failed and the mock response should be served instead.
+func (p *upstreamPool) ServeHTTP(writer http.ResponseWriter, req *http.Request) bool {
+	body, err := peekBody(req)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return true
+	}
+
+	candidates := p.candidates(time.Now())
+
+	for idx, target := range candidates {
+		attempt := &upstreamAttempt{statuses: p.statuses, last: idx == len(candidates)-1 && !p.fallback}
+
+		outreq := req.Clone(context.WithValue(req.Context(), upstreamAttemptKey{}, attempt))
+		outreq.Body = io.NopCloser(bytes.NewReader(body))
+
+		target.proxy.ServeHTTP(writer, outreq)
+
+		if attempt.err == nil {
+			p.report(target, true)
+
+			return true
+		}
+
+		if req.Context().Err() != nil {
+			return true
+		}
+
+		p.report(target, false)
+		p.log.Warn("upstream failed", "target", target.proxy.target.String(), "error", attempt.err)
+	}
+
+	if p.fallback {
+		p.log.Debug("every upstream failed, serving the mock response")
+
+		return false
+	}
+
+	http.Error(writer, "no upstream is available", http.StatusBadGateway)
+
+	return true
+}
+
+// candidates returns healthy targets, starting with the selected one.
+func (p *upstreamPool) candidates(now time.Time) []*upstream {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	healthy := make([]*upstream, 0, len(p.targets))
+
+	for _, target := range p.targets {
+		if !now.Before(target.downUntil) {
+			healthy = append(healthy, target)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	first := 0
+
+	switch p.strategy {
+	case strategyRoundRobin:
+		first = p.next % len(healthy)
+		p.next++
+	case strategyRandom:
+		first = p.rnd.IntN(len(healthy))
+	case strategyWeighted:
+		first = p.selectWeighted(healthy)
+	}
+
+	return slices.Concat(healthy[first:], healthy[:first])
+}
+
+// selectWeighted implements smooth weighted round robin, which spreads heavier targets evenly.
+func (p *upstreamPool) selectWeighted(healthy []*upstream) int {
+	total, best := 0, 0
+
+	for idx, target := range healthy {
+		target.current += target.weight
+		total += target.weight
+
+		if target.current > healthy[best].current {
+			best = idx
+		}
+	}
+
+	healthy[best].current -= total
+
+	return best
+}
+
+// report updates passive health of the target, consecutive failures mark it down for the fail timeout.
+func (p *upstreamPool) report(target *upstream, success bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if success {
+		target.fails = 0
+
+		return
+	}
+
+	target.fails++
+
+	if target.fails >= p.maxFails {
+		target.fails = 0
+		target.downUntil = time.Now().Add(p.timeout)
+	}
+}
@@ -0,0 +1,126 @@
+package app_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newUpstream(t *testing.T, name string, status int, hits *atomic.Int32) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		writer.WriteHeader(status)
+		_, _ = writer.Write([]byte(name))
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func closedUpstream(t *testing.T) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	return server.URL
+}
+
+func TestUpstreams_Strategies(t *testing.T) {
+	t.Parallel()
+
+	var hitsA, hitsB atomic.Int32
+
+	urlA := newUpstream(t, "a", http.StatusOK, &hitsA)
+	urlB := newUpstream(t, "b", http.StatusOK, &hitsB)
+
+	handler := newMockHandler(t, t.TempDir(), fmt.Sprintf(`{"endpoints": [
+		{"path": "/rr", "upstreams": {"targets": [{"url": %[1]q}, {"url": %[2]q}]}},
+		{"path": "/weighted", "upstreams": {"strategy": "weighted", "targets": [
+			{"url": %[1]q, "weight": 2}, {"url": %[2]q}
+		]}},
+		{"path": "/random", "upstreams": {"strategy": "random", "targets": [{"url": %[1]q}, {"url": %[2]q}]}}
+	]}`, urlA, urlB))
+
+	var bodies []string
+	for range 4 {
+		bodies = append(bodies, serveWith(handler, "/rr", nil).Body.String())
+	}
+
+	assert.Equal(t, "a,b,a,b", strings.Join(bodies, ","))
+
+	bodies = nil
+	for range 6 {
+		bodies = append(bodies, serveWith(handler, "/weighted", nil).Body.String())
+	}
+
+	assert.Equal(t, "a,b,a,a,b,a", strings.Join(bodies, ","))
+
+	for range 4 {
+		assert.Contains(t, []string{"a", "b"}, serveWith(handler, "/random", nil).Body.String())
+	}
+}
+
+func TestUpstreams_Failover(t *testing.T) {
+	t.Parallel()
+
+	var hitsA, hitsB atomic.Int32
+
+	urlA := newUpstream(t, "a", http.StatusServiceUnavailable, &hitsA)
+	urlB := newUpstream(t, "b", http.StatusOK, &hitsB)
+
+	handler := newMockHandler(t, t.TempDir(), fmt.Sprintf(`{"endpoints": [
+		{"methods": ["POST"], "path": "/orders", "upstreams": {
+			"targets": [{"url": %q}, {"url": %q}, {"url": %q}],
+			"failTimeout": "1m"
+		}}
+	]}`, closedUpstream(t), urlA, urlB))
+
+	for range 3 {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"id": 1}`))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "b", rec.Body.String())
+	}
+
+	// Failed targets are skipped until the fail timeout passes.
+	assert.Equal(t, int32(1), hitsA.Load())
+	assert.Equal(t, int32(3), hitsB.Load())
+}
+
+func TestUpstreams_AllFail(t *testing.T) {
+	t.Parallel()
+
+	var hitsA, hitsB atomic.Int32
+
+	urlA := newUpstream(t, "a", http.StatusInternalServerError, &hitsA)
+	urlB := newUpstream(t, "b", http.StatusBadGateway, &hitsB)
+
+	handler := newMockHandler(t, t.TempDir(), fmt.Sprintf(`{"endpoints": [
+		{"path": "/mocked", "body": "mock", "upstreams": {"targets": [{"url": %[1]q}, {"url": %[2]q}], "fallback": true}},
+		{"path": "/passed", "upstreams": {"targets": [{"url": %[1]q}, {"url": %[2]q}], "failTimeout": "0s"}},
+		{"path": "/down", "upstreams": {"targets": [{"url": %[3]q}]}}
+	]}`, urlA, urlB, closedUpstream(t)))
+
+	rec := serveWith(handler, "/mocked", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "mock", rec.Body.String())
+
+	// The last target responds as it is without the mock fallback.
+	rec = serveWith(handler, "/passed", nil)
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, "b", rec.Body.String())
+
+	rec = serveWith(handler, "/down", nil)
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, "no upstream is available\n", rec.Body.String())
+}
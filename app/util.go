@@ -1,9 +1,12 @@
 package app
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 )
 
@@ -18,6 +21,10 @@ func (wrp *responseWriterWrapper) WriteHeader(code int) {
 	wrp.statusCode = code
 }
 
+func (wrp *responseWriterWrapper) Unwrap() http.ResponseWriter {
+	return wrp.ResponseWriter
+}
+
 func readRequestJSON(_ context.Context, req *http.Request, object any) *appError {
 	err := json.NewDecoder(req.Body).Decode(object)
 	if err != nil {
@@ -30,3 +37,61 @@ func readRequestJSON(_ context.Context, req *http.Request, object any) *appError
 
 	return nil
 }
+
+// encodeJSON encodes the value the same way as writeResponse does.
+func encodeJSON(value any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := json.NewEncoder(&buf).Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("encoding JSON: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// peekBody reads the whole request body and puts it back, so that it can be read again.
+func peekBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body = io.NopCloser(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %w", err)
+	}
+
+	return data, nil
+}
+
+// peekBodyPrefix reads at most limit bytes of the request body and puts them back in front of the rest, so that
+// the whole body can be read again, reports if the body is longer than limit.
+func peekBodyPrefix(req *http.Request, limit int) ([]byte, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, false, nil
+	}
+
+	// One byte more tells if the body is longer than limit.
+	size := int64(limit)
+	if size < math.MaxInt64 {
+		size++
+	}
+
+	data, err := io.ReadAll(io.LimitReader(req.Body, size))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), req.Body), req.Body}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("reading request body: %w", err)
+	}
+
+	if len(data) > limit {
+		return data[:limit], true, nil
+	}
+
+	return data, false, nil
+}
@@ -0,0 +1,317 @@
+package app
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	errXPathNotAbsolute = errors.New("XPath expression must start with '/'")
+	errXPathEmptyStep   = errors.New("empty step in XPath expression")
+	errXPathPredicate   = errors.New("unsupported predicate in XPath expression")
+	errXPathBrackets    = errors.New("unbalanced brackets in XPath expression")
+)
+
+// xmlNode is an element of a parsed XML document, names are local, i.e. without namespace prefixes.
+type xmlNode struct {
+	name     string
+	attrs    map[string]string
+	children []*xmlNode
+	text     strings.Builder
+}
+
+// parseXML parses XML into a document node, which holds the root element as its only child.
+func parseXML(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	doc := &xmlNode{}
+	stack := []*xmlNode{doc}
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("parsing XML: %w", err)
+		}
+
+		current := stack[len(stack)-1]
+
+		switch tok := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: tok.Name.Local, attrs: make(map[string]string, len(tok.Attr))}
+			for _, attr := range tok.Attr {
+				node.attrs[attr.Name.Local] = attr.Value
+			}
+
+			current.children = append(current.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			for _, node := range stack[1:] {
+				node.text.Write(tok)
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+func (n *xmlNode) value() string {
+	return strings.TrimSpace(n.text.String())
+}
+
+// xpath is a compiled subset of XPath: absolute location paths of child ("/") and descendant ("//") steps
+// with element names (namespace prefixes are ignored) or "*", position ("[2]"), attribute ("[@id='1']")
+// and child value ("[name='foo']") predicates, ending optionally with "@attr" or "text()".
+type xpath struct {
+	expr  string
+	steps []xpathStep
+}
+
+type xpathStep struct {
+	descendant bool
+	name       string // element name, "*", "@attr" or "text()"
+	predicates []xpathPredicate
+}
+
+type xpathPredicate struct {
+	position int
+	attr     string
+	child    string
+	value    string
+}
+
+func compileXPath(expr string) (*xpath, error) {
+	if !strings.HasPrefix(expr, "/") {
+		return nil, fmt.Errorf("%w: [%s]", errXPathNotAbsolute, expr)
+	}
+
+	compiled := &xpath{expr: expr}
+	rest := expr
+
+	for rest != "" {
+		step := xpathStep{}
+
+		rest = strings.TrimPrefix(rest, "/")
+		if strings.HasPrefix(rest, "/") {
+			step.descendant = true
+			rest = rest[1:]
+		}
+
+		raw, tail, err := splitXPathStep(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: [%s]", err, expr)
+		}
+
+		rest = tail
+
+		err = step.parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: [%s]", err, expr)
+		}
+
+		compiled.steps = append(compiled.steps, step)
+	}
+
+	return compiled, nil
+}
+
+// splitXPathStep splits off the first step, slashes inside predicates don't end the step.
+func splitXPathStep(expr string) (string, string, error) {
+	depth := 0
+	quote := rune(0)
+
+	for idx, char := range expr {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == '[':
+			depth++
+		case char == ']':
+			depth--
+		case char == '/' && depth == 0:
+			return expr[:idx], expr[idx:], nil
+		}
+	}
+
+	if depth != 0 || quote != 0 {
+		return "", "", errXPathBrackets
+	}
+
+	return expr, "", nil
+}
+
+func (s *xpathStep) parse(raw string) error {
+	name, predicates, _ := strings.Cut(raw, "[")
+	if name == "" {
+		return errXPathEmptyStep
+	}
+
+	if _, local, found := strings.Cut(name, ":"); found && !strings.HasPrefix(name, "@") {
+		name = local
+	}
+
+	s.name = name
+
+	if predicates == "" {
+		return nil
+	}
+
+	for part := range strings.SplitSeq(strings.TrimSuffix(predicates, "]"), "][") {
+		predicate, err := parseXPathPredicate(strings.TrimSpace(part))
+		if err != nil {
+			return err
+		}
+
+		s.predicates = append(s.predicates, predicate)
+	}
+
+	return nil
+}
+
+func parseXPathPredicate(raw string) (xpathPredicate, error) {
+	if position, err := strconv.Atoi(raw); err == nil {
+		return xpathPredicate{position: position}, nil
+	}
+
+	left, right, found := strings.Cut(raw, "=")
+	if !found {
+		return xpathPredicate{}, fmt.Errorf("%w: [%s]", errXPathPredicate, raw)
+	}
+
+	left = strings.TrimSpace(left)
+	value := strings.Trim(strings.TrimSpace(right), `'"`)
+
+	if attr, isAttr := strings.CutPrefix(left, "@"); isAttr {
+		return xpathPredicate{attr: attr, value: value}, nil
+	}
+
+	if _, local, hasPrefix := strings.Cut(left, ":"); hasPrefix {
+		left = local
+	}
+
+	return xpathPredicate{child: left, value: value}, nil
+}
+
+// eval returns values of the nodes selected by the expression: texts of elements or values of attributes.
+func (x *xpath) eval(doc *xmlNode) []string {
+	nodes := []*xmlNode{doc}
+
+	for idx, step := range x.steps {
+		last := idx == len(x.steps)-1
+
+		switch {
+		case last && step.name == "text()":
+			return nodeValues(nodes)
+		case last && strings.HasPrefix(step.name, "@"):
+			var values []string
+
+			for _, node := range nodes {
+				if value, ok := node.attrs[step.name[1:]]; ok {
+					values = append(values, value)
+				}
+			}
+
+			return values
+		}
+
+		var next []*xmlNode
+		for _, node := range nodes {
+			next = append(next, step.selectFrom(node)...)
+		}
+
+		nodes = next
+	}
+
+	return nodeValues(nodes)
+}
+
+func (s *xpathStep) selectFrom(node *xmlNode) []*xmlNode {
+	var candidates []*xmlNode
+
+	if s.descendant {
+		candidates = descendants(node)
+	} else {
+		candidates = node.children
+	}
+
+	var selected []*xmlNode
+
+	for _, candidate := range candidates {
+		if s.name == "*" || candidate.name == s.name {
+			selected = append(selected, candidate)
+		}
+	}
+
+	for _, predicate := range s.predicates {
+		selected = predicate.filter(selected)
+	}
+
+	return selected
+}
+
+func (p xpathPredicate) filter(nodes []*xmlNode) []*xmlNode {
+	if p.position > 0 {
+		if p.position > len(nodes) {
+			return nil
+		}
+
+		return nodes[p.position-1 : p.position]
+	}
+
+	var filtered []*xmlNode
+
+	for _, node := range nodes {
+		if p.attr != "" && node.attrs[p.attr] == p.value {
+			filtered = append(filtered, node)
+
+			continue
+		}
+
+		for _, child := range node.children {
+			if p.child != "" && child.name == p.child && child.value() == p.value {
+				filtered = append(filtered, node)
+
+				break
+			}
+		}
+	}
+
+	return filtered
+}
+
+func descendants(node *xmlNode) []*xmlNode {
+	var result []*xmlNode
+
+	for _, child := range node.children {
+		result = append(result, child)
+		result = append(result, descendants(child)...)
+	}
+
+	return result
+}
+
+func nodeValues(nodes []*xmlNode) []string {
+	values := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		values = append(values, node.value())
+	}
+
+	return values
+}
@@ -0,0 +1,48 @@
+package app //nolint:testpackage // testing unexported XPath internals
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testEnvelope = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:m="urn:users">
+  <soap:Body>
+    <m:GetUsers>
+      <m:user id="1"><m:name>Ann</m:name></m:user>
+      <m:user id="2"><m:name>Bob</m:name></m:user>
+    </m:GetUsers>
+  </soap:Body>
+</soap:Envelope>`
+
+func Test_XPath(t *testing.T) {
+	t.Parallel()
+
+	doc, err := parseXML([]byte(testEnvelope))
+	require.NoError(t, err)
+
+	for expr, expected := range map[string][]string{
+		"/Envelope/Body/GetUsers/user/name":        {"Ann", "Bob"},
+		"/soap:Envelope/soap:Body/m:GetUsers/user": {"Ann", "Bob"},
+		"//user[2]/name/text()":                    {"Bob"},
+		"//user[@id='2']/name":                     {"Bob"},
+		"//user[name='Ann']/@id":                   {"1"},
+		"/Envelope/*/GetUsers/user[1]/@id":         {"1"},
+		"//missing":                                {},
+	} {
+		compiled, err := compileXPath(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, expected, compiled.eval(doc), expr)
+	}
+}
+
+func Test_XPath_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{"Envelope", "/Envelope//", "/user[@id='1'", "/user[foo]"} {
+		_, err := compileXPath(expr)
+		assert.Error(t, err, expr)
+	}
+}
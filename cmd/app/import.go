@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/smeshkov/gomock/app"
+	"github.com/smeshkov/gomock/config"
+)
+
+var errNoImportFiles = errors.New("no files to import")
+
+// runImport converts HAR, gomock capture, Postman collection or cURL commands files to endpoints
+// and merges them into the mock file.
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	mockFile := flags.String("mock", "mock.json", "Mock configuration file to merge endpoints into, created if missing")
+
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: gomock import [-mock mock.json] file [file...]")
+		_, _ = fmt.Fprintln(flags.Output(), "Files are HAR, gomock capture, Postman v2.1 collection or cURL commands.")
+		flags.PrintDefaults()
+	}
+
+	_ = flags.Parse(args) // exits on errors
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+
+		return errNoImportFiles
+	}
+
+	var endpoints []*config.Endpoint
+
+	for _, file := range flags.Args() {
+		imported, err := app.ImportFile(file)
+		if err != nil {
+			return fmt.Errorf("importing %s: %w", file, err)
+		}
+
+		endpoints = append(endpoints, imported...)
+	}
+
+	added, err := config.MergeEndpoints(*mockFile, endpoints)
+	if err != nil {
+		return fmt.Errorf("importing into %s: %w", *mockFile, err)
+	}
+
+	slog.Info(fmt.Sprintf("imported %d endpoints into %s, %d routes already existed",
+		added, *mockFile, len(endpoints)-added))
+
+	return nil
+}
+
+// runCommand runs the subcommand given as the first argument, returns false if there is none.
+func runCommand(args []string) bool {
+	if len(args) == 0 || args[0] != "import" {
+		return false
+	}
+
+	config.SetupLog("info")
+
+	err := runImport(args[1:])
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+
+	return true
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
@@ -25,6 +26,10 @@ const shutdownTimeout = 5 * time.Second
 var version = "untagged"
 
 func main() {
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
 	mockFile := flag.String("mock", "mock.json", "Mock configuration file")
 	verbose := flag.Bool("verbose", false, "Verbose")
 	ver := flag.Bool("version", false, "prints version of gomock")
@@ -35,6 +40,12 @@ func main() {
 	flagReadTimeout := flag.String("read-timeout", "", "Read timeout as Go duration e.g. 10s (overrides mock config)")
 	flagWriteTimeout := flag.String("write-timeout", "", "Write timeout as Go duration e.g. 10s (overrides mock config)")
 	flagIdleTimeout := flag.String("idle-timeout", "", "Idle timeout as Go duration e.g. 60s (overrides mock config)")
+	flagAccessLog := flag.String("access-log", "", "Access log output: stdout, stderr or a file path (overrides mock config)")
+	flagAccessFormat := flag.String("access-log-format", "",
+		"Access log format: combined, json or logfmt (overrides mock config)")
+	flagRecord := flag.String("record", "", "Records served traffic to the file in gomock capture format on shutdown")
+	flagReplay := flag.String("replay", "",
+		"Replays responses recorded in the HAR or gomock capture file (overrides mock config)")
 
 	flag.Parse()
 
@@ -54,22 +65,45 @@ func main() {
 		ReadTimeout:  *flagReadTimeout,
 		WriteTimeout: *flagWriteTimeout,
 		IdleTimeout:  *flagIdleTimeout,
+		AccessLog:    *flagAccessLog,
+		AccessFormat: *flagAccessFormat,
+	}
+
+	if *flagReplay != "" {
+		// Relative to the working directory rather than the mock file.
+		replay, err := filepath.Abs(*flagReplay)
+		if err != nil {
+			slog.Error(fmt.Sprintf("failed to resolve replay file %s: %v", *flagReplay, err))
+			os.Exit(1)
+		}
+
+		overrides.Replay = replay
 	}
 
-	serverLoop(*mockFile, *watch, overrides)
+	serverLoop(*mockFile, *watch, overrides, *flagRecord)
 }
 
-func serverLoop(mockFile string, watch bool, overrides config.CLIOverrides) {
+func serverLoop(mockFile string, watch bool, overrides config.CLIOverrides, record string) {
 	// Channel to handle termination signals.
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	// Journal outlives restarts of the server, so that the whole session is recorded.
+	var journal *app.Journal
+	if record != "" {
+		journal = app.NewJournal(0)
+		// Recordings are replayed, so bodies are kept whole.
+		journal.SetBodyLimit(0)
+	}
+
 	for {
 		mck, mockPath, err := config.NewMock(mockFile)
 		if err != nil {
 			slog.Warn(fmt.Sprintf("failed to load mock configuration %s: %v", mockFile, err))
 		}
 
+		mck.ApplyOverrides(overrides)
+
 		cfg := mck.ToConfig()
 		cfg.ApplyOverrides(overrides)
 		config.SetupLog(cfg.Logger.Level)
@@ -80,7 +114,7 @@ func serverLoop(mockFile string, watch bool, overrides config.CLIOverrides) {
 		var waitGroup sync.WaitGroup
 
 		waitGroup.Go(func() {
-			runServer(serverCtx, &cfg, &mck, version, mockPath)
+			runServer(serverCtx, &cfg, &mck, mockPath, journal)
 		})
 
 		if watch {
@@ -92,6 +126,7 @@ func serverLoop(mockFile string, watch bool, overrides config.CLIOverrides) {
 			slog.Info("received termination signal, shutting down...")
 			cancelServer()
 			waitGroup.Wait()
+			saveRecording(journal, record)
 
 			return
 		case <-serverCtx.Done():
@@ -102,14 +137,22 @@ func serverLoop(mockFile string, watch bool, overrides config.CLIOverrides) {
 }
 
 // runServer starts the HTTP server and blocks until ctx is cancelled.
-func runServer(ctx context.Context, cfg *config.Config, mck *config.Mock, ver, mockPath string) {
+func runServer(ctx context.Context, cfg *config.Config, mck *config.Mock, mockPath string, journal *app.Journal) {
+	accessLog, err := app.NewAccessLog(cfg.AccessLog)
+	if err != nil {
+		slog.Error(fmt.Sprintf("failed to set up access log: %v", err))
+		os.Exit(1)
+	}
+
+	defer func() { _ = accessLog.Close() }()
+
 	srv := &http.Server{
 		ReadHeaderTimeout: cfg.Server.ReadTimeout,
 		IdleTimeout:       cfg.Server.IdleTimeout,
 		ReadTimeout:       cfg.Server.ReadTimeout,
 		WriteTimeout:      cfg.Server.WriteTimeout,
 		Addr:              cfg.Server.Addr,
-		Handler:           app.RegisterHandlers(ver, mockPath, cfg, mck),
+		Handler:           accessLog.Middleware(journal.Middleware(app.RegisterHandlers(version, mockPath, cfg, mck))),
 	}
 
 	go func() {
@@ -128,7 +171,7 @@ func runServer(ctx context.Context, cfg *config.Config, mck *config.Mock, ver, m
 
 	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
 
-	err := srv.Shutdown(shutdownCtx)
+	err = srv.Shutdown(shutdownCtx)
 
 	cancel()
 
@@ -138,6 +181,22 @@ func runServer(ctx context.Context, cfg *config.Config, mck *config.Mock, ver, m
 	}
 }
 
+// saveRecording writes traffic recorded by the journal to the file.
+func saveRecording(journal *app.Journal, file string) {
+	if journal == nil {
+		return
+	}
+
+	err := journal.Save(file)
+	if err != nil {
+		slog.Error(fmt.Sprintf("failed to save recorded traffic: %v", err))
+
+		return
+	}
+
+	slog.Info(fmt.Sprintf("recorded %d requests to %s", len(journal.Entries()), file))
+}
+
 // watchConfigFiles monitors configuration file changes and restarts server.
 func watchConfigFiles(mockPath string, cancelServer context.CancelFunc) {
 	err := watchLoop(mockPath, cancelServer)
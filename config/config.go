@@ -17,6 +17,7 @@ type Config struct {
 	Logger struct {
 		Level string
 	}
+	AccessLog *AccessLog
 }
 
 // CLIOverrides holds CLI flag values that override config settings.
@@ -28,6 +29,9 @@ type CLIOverrides struct {
 	ReadTimeout  string
 	WriteTimeout string
 	IdleTimeout  string
+	AccessLog    string
+	AccessFormat string
+	Replay       string // capture file to replay, an absolute path
 }
 
 // ApplyOverrides applies CLI flag overrides to the config.
@@ -62,4 +66,27 @@ func (c *Config) ApplyOverrides(overrides CLIOverrides) {
 	if overrides.Verbose {
 		c.Logger.Level = "debug"
 	}
+
+	c.applyAccessLogOverrides(overrides)
+}
+
+func (c *Config) applyAccessLogOverrides(overrides CLIOverrides) {
+	if overrides.AccessLog == "" && overrides.AccessFormat == "" {
+		return
+	}
+
+	accessLog := AccessLog{}
+	if c.AccessLog != nil {
+		accessLog = *c.AccessLog
+	}
+
+	if overrides.AccessLog != "" {
+		accessLog.Output = overrides.AccessLog
+	}
+
+	if overrides.AccessFormat != "" {
+		accessLog.Format = overrides.AccessFormat
+	}
+
+	c.AccessLog = &accessLog
 }
@@ -0,0 +1,354 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// mockFilePerm is the permission of mock files created by imports.
+const mockFilePerm = 0o644
+
+var errMockNotObject = errors.New("mock isn't a JSON object")
+
+// routeParam matches URL parameters of paths, names of parameters don't tell routes apart.
+var routeParam = regexp.MustCompile(`\{[^}]*\}`)
+
+// RouteKey identifies the route of the endpoint by its methods, path and match conditions.
+func (e *Endpoint) RouteKey() string {
+	return strings.Join(e.methods(), ",") + e.routeSuffix()
+}
+
+// RouteKeys identifies the routes of the endpoint, one per method, by the method, path and match conditions.
+func (e *Endpoint) RouteKeys() []string {
+	methods := e.methods()
+
+	suffix := e.routeSuffix()
+	for idx, method := range methods {
+		methods[idx] = method + suffix
+	}
+
+	return methods
+}
+
+// methods returns sorted upper-case methods of the endpoint, GET if there are none.
+func (e *Endpoint) methods() []string {
+	methods := slices.Clone(e.Methods)
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet}
+	}
+
+	for idx, method := range methods {
+		methods[idx] = strings.ToUpper(method)
+	}
+
+	slices.Sort(methods)
+
+	return methods
+}
+
+// routeSuffix identifies the route of the endpoint by its path and match conditions.
+func (e *Endpoint) routeSuffix() string {
+	key := " " + routeParam.ReplaceAllString(e.Path, "{}")
+
+	if e.Match != nil {
+		key += " " + e.Match.key()
+	}
+
+	return key
+}
+
+func (m *Match) key() string {
+	var parts []string
+
+	for kind, values := range map[string]map[string]string{
+		"headers": m.Headers, "xpath": m.XPath, "form": m.Form, "claims": m.Claims,
+	} {
+		for name, value := range values {
+			parts = append(parts, kind+"."+name+"="+value)
+		}
+	}
+
+	if m.SOAPAction != "" {
+		parts = append(parts, "soapAction="+m.SOAPAction)
+	}
+
+	slices.Sort(parts)
+
+	return strings.Join(parts, "&")
+}
+
+// MergeEndpoints appends the endpoints to the mock file, skipping the ones with routes already in the file,
+// and returns the number of added endpoints. Added endpoints are spliced into the endpoints array, so that
+// the rest of the file keeps its order and formatting, the file is created if it doesn't exist.
+func MergeEndpoints(file string, endpoints []*Endpoint) (int, error) {
+	data, err := os.ReadFile(filepath.Clean(file))
+
+	exists := true
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		exists = false
+	case err != nil:
+		return 0, fmt.Errorf("reading mock file: %w", err)
+	}
+
+	layout := &mockLayout{endpointsAt: -1}
+
+	if exists {
+		layout, err = scanMock(data)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	routes := map[string]bool{}
+
+	if layout.endpoints != nil {
+		var decoded []*Endpoint
+
+		err = json.Unmarshal(layout.endpoints, &decoded)
+		if err != nil {
+			return 0, fmt.Errorf("unmarshalling endpoints: %w", err)
+		}
+
+		for _, endpoint := range decoded {
+			for _, key := range endpoint.RouteKeys() {
+				routes[key] = true
+			}
+		}
+	}
+
+	added := []*Endpoint{}
+
+	for _, endpoint := range endpoints {
+		keys := endpoint.RouteKeys()
+		if slices.ContainsFunc(keys, func(key string) bool { return routes[key] }) {
+			continue
+		}
+
+		for _, key := range keys {
+			routes[key] = true
+		}
+
+		added = append(added, endpoint)
+	}
+
+	if exists {
+		data, err = layout.splice(data, added)
+	} else {
+		data, err = encodeJSON(map[string]any{"endpoints": added}, "  ")
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.WriteFile(filepath.Clean(file), data, mockFilePerm)
+	if err != nil {
+		return 0, fmt.Errorf("writing mock file: %w", err)
+	}
+
+	return len(added), nil
+}
+
+// mockLayout tells where the endpoints are in the mock file and how the file is indented.
+type mockLayout struct {
+	objectEnd   int             // offset of the closing brace of the mock
+	members     int             // number of top level keys
+	multiline   bool            // top level keys are on their own lines
+	indent      string          // indentation of top level keys
+	endpoints   json.RawMessage // value of "endpoints", nil if there is none
+	endpointsAt int             // offset of the value of "endpoints", -1 if there is none
+}
+
+// scanMock finds the endpoints in the mock file without decoding the rest of it.
+func scanMock(data []byte) (*mockLayout, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling mock JSON: %w", err)
+	}
+
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("unmarshalling mock JSON: %w", errMockNotObject)
+	}
+
+	layout := &mockLayout{endpointsAt: -1}
+	layout.indent, layout.multiline = lineIndent(data[decoder.InputOffset():])
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("unmarshalling mock JSON: %w", err)
+		}
+
+		var value json.RawMessage
+
+		err = decoder.Decode(&value)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshalling mock JSON: %w", err)
+		}
+
+		layout.members++
+
+		if key == "endpoints" {
+			layout.endpoints = value
+			layout.endpointsAt = int(decoder.InputOffset()) - len(value)
+		}
+	}
+
+	_, err = decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling mock JSON: %w", err)
+	}
+
+	layout.objectEnd = int(decoder.InputOffset()) - 1
+
+	return layout, nil
+}
+
+// splice inserts the endpoints into data of the mock file, indented like the file.
+func (l *mockLayout) splice(data []byte, endpoints []*Endpoint) ([]byte, error) {
+	if len(endpoints) == 0 {
+		return data, nil
+	}
+
+	// Appended to the array, indented like its first element.
+	if len(l.endpoints) > 0 && l.endpoints[0] == '[' {
+		inner := l.endpoints[1 : len(l.endpoints)-1]
+
+		if len(bytes.TrimSpace(inner)) > 0 {
+			prefix, multiline := lineIndent(inner)
+
+			elements, err := encodeElements(endpoints, multiline, prefix, l.indent)
+			if err != nil {
+				return nil, err
+			}
+
+			at := l.endpointsAt + 1 + len(bytes.TrimRightFunc(inner, unicode.IsSpace))
+
+			return insert(data, at, ","+separator(multiline, prefix)+elements), nil
+		}
+	}
+
+	array, err := l.array(endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replaces an empty array or null.
+	if l.endpoints != nil {
+		return slices.Concat(data[:l.endpointsAt], []byte(array), data[l.endpointsAt+len(l.endpoints):]), nil
+	}
+
+	member := `"endpoints": ` + array
+	at := len(bytes.TrimRightFunc(data[:l.objectEnd], unicode.IsSpace))
+
+	switch {
+	case l.members > 0:
+		member = "," + separator(l.multiline, l.indent) + member
+	case l.multiline:
+		member = "\n" + l.indent + member + "\n"
+	}
+
+	return insert(data, at, member), nil
+}
+
+// array encodes the endpoints as a new array of the top level key.
+func (l *mockLayout) array(endpoints []*Endpoint) (string, error) {
+	if len(endpoints) == 0 {
+		return "[]", nil
+	}
+
+	elements, err := encodeElements(endpoints, l.multiline, l.indent+l.indent, l.indent)
+	if err != nil {
+		return "", err
+	}
+
+	if !l.multiline {
+		return "[" + elements + "]", nil
+	}
+
+	return "[\n" + l.indent + l.indent + elements + "\n" + l.indent + "]", nil
+}
+
+// encodeElements encodes the endpoints as elements of an array, each on its own line with the prefix
+// if multiline is set, on a single line otherwise.
+func encodeElements(endpoints []*Endpoint, multiline bool, prefix, indent string) (string, error) {
+	elements := make([]string, 0, len(endpoints))
+
+	for _, endpoint := range endpoints {
+		raw, err := encodeJSON(endpoint, "")
+		if err != nil {
+			return "", fmt.Errorf("encoding endpoint [%s]: %w", endpoint.Path, err)
+		}
+
+		raw = bytes.TrimSpace(raw)
+
+		if multiline {
+			var buf bytes.Buffer
+
+			err = json.Indent(&buf, raw, prefix, indent)
+			if err != nil {
+				return "", fmt.Errorf("encoding endpoint [%s]: %w", endpoint.Path, err)
+			}
+
+			raw = buf.Bytes()
+		}
+
+		elements = append(elements, string(raw))
+	}
+
+	return strings.Join(elements, ","+separator(multiline, prefix)), nil
+}
+
+// lineIndent returns indentation of the line of the first value in data, multiline tells if the value
+// is on a line of its own.
+func lineIndent(data []byte) (string, bool) {
+	space := data[:len(data)-len(bytes.TrimLeftFunc(data, unicode.IsSpace))]
+
+	idx := bytes.LastIndexByte(space, '\n')
+	if idx < 0 {
+		return "", false
+	}
+
+	return string(space[idx+1:]), true
+}
+
+func separator(multiline bool, prefix string) string {
+	if multiline {
+		return "\n" + prefix
+	}
+
+	return " "
+}
+
+func insert(data []byte, at int, text string) []byte {
+	return slices.Concat(data[:at], []byte(text), data[at:])
+}
+
+// encodeJSON encodes the value without escaping HTML, so that imported HTML and XML bodies stay readable.
+func encodeJSON(value any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+
+	err := encoder.Encode(value)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by callers
+	}
+
+	return buf.Bytes(), nil
+}
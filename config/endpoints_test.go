@@ -0,0 +1,135 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock/config"
+)
+
+func TestEndpointRouteKey(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		(&config.Endpoint{Path: "/users"}).RouteKey(),
+		(&config.Endpoint{Path: "/users", Methods: []string{"get"}}).RouteKey())
+	assert.Equal(t,
+		(&config.Endpoint{Path: "/users", Methods: []string{"PUT", "POST"}}).RouteKey(),
+		(&config.Endpoint{Path: "/users", Methods: []string{"post", "put"}}).RouteKey())
+	assert.Equal(t,
+		(&config.Endpoint{Path: "/users/{id}"}).RouteKey(),
+		(&config.Endpoint{Path: "/users/{userId}"}).RouteKey())
+	assert.NotEqual(t,
+		(&config.Endpoint{Path: "/users"}).RouteKey(),
+		(&config.Endpoint{Path: "/users", Match: &config.Match{Headers: map[string]string{"X-Version": "2"}}}).RouteKey())
+	assert.NotEqual(t,
+		(&config.Endpoint{Path: "/reports", Match: &config.Match{Claims: map[string]string{"role": "admin"}}}).RouteKey(),
+		(&config.Endpoint{Path: "/reports", Match: &config.Match{Claims: map[string]string{"role": "user"}}}).RouteKey())
+}
+
+func TestMergeEndpoints(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "mock.json")
+
+	added, err := config.MergeEndpoints(file, []*config.Endpoint{{Path: "/users", Body: "<users/>"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"body": "<users/>"`)
+
+	require.NoError(t, os.WriteFile(file, []byte(`{"port": 3000, "$schema": "gomock.json", "endpoints": [
+		{"path": "/health", "json": {"ok": true}},
+		{"path": "/users/{id}", "methods": ["get"]}
+	]}`), 0o600))
+
+	added, err = config.MergeEndpoints(file, []*config.Endpoint{
+		{Path: "/health", Status: 204},
+		{Path: "/users/{userId}"},
+		{Path: "/users", Methods: []string{"POST"}, Status: 201},
+		{Path: "/users", Methods: []string{"POST"}, Status: 400},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	mck, _, err := config.NewMock(file)
+	require.NoError(t, err)
+	assert.Equal(t, 3000, mck.Port)
+	require.Len(t, mck.Endpoints, 3)
+	assert.Equal(t, "/health", mck.Endpoints[0].Path)
+	assert.Equal(t, 0, mck.Endpoints[0].Status)
+	assert.Equal(t, 201, mck.Endpoints[2].Status)
+
+	data, err = os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"$schema": "gomock.json"`)
+}
+
+func TestMergeEndpoints_MethodsOfRoute(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "mock.json")
+
+	require.NoError(t, os.WriteFile(file, []byte(`{"endpoints": [
+		{"path": "/users", "methods": ["GET", "POST"]}
+	]}`), 0o600))
+
+	added, err := config.MergeEndpoints(file, []*config.Endpoint{
+		{Path: "/users"},
+		{Path: "/users", Methods: []string{"post"}},
+		{Path: "/users", Methods: []string{"PUT", "POST"}},
+		{Path: "/users", Methods: []string{"DELETE"}},
+		{Path: "/users", Match: &config.Match{Headers: map[string]string{"X-Version": "2"}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	mck, _, err := config.NewMock(file)
+	require.NoError(t, err)
+	require.Len(t, mck.Endpoints, 3)
+	assert.Equal(t, []string{"DELETE"}, mck.Endpoints[1].Methods)
+	assert.NotNil(t, mck.Endpoints[2].Match)
+}
+
+func TestMergeEndpoints_KeepsLayout(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	for name, test := range map[string]struct{ before, after string }{
+		"appended": {
+			before: "{\n  \"port\": 3000,\n  \"endpoints\": [\n    {\"path\": \"/health\"}\n  ],\n  \"delay\": 10\n}\n",
+			after: "{\n  \"port\": 3000,\n  \"endpoints\": [\n    {\"path\": \"/health\"},\n    {\n" +
+				"      \"path\": \"/users\"\n    }\n  ],\n  \"delay\": 10\n}\n",
+		},
+		"empty array": {
+			before: "{\n  \"port\": 3000,\n  \"endpoints\": []\n}\n",
+			after:  "{\n  \"port\": 3000,\n  \"endpoints\": [\n    {\n      \"path\": \"/users\"\n    }\n  ]\n}\n",
+		},
+		"no endpoints": {
+			before: "{\n  \"port\": 3000\n}\n",
+			after:  "{\n  \"port\": 3000,\n  \"endpoints\": [\n    {\n      \"path\": \"/users\"\n    }\n  ]\n}\n",
+		},
+		"one line": {
+			before: `{"endpoints": [{"path": "/health"}], "port": 3000}`,
+			after:  `{"endpoints": [{"path": "/health"}, {"path":"/users"}], "port": 3000}`,
+		},
+	} {
+		file := filepath.Join(dir, name+".json")
+		require.NoError(t, os.WriteFile(file, []byte(test.before), 0o600))
+
+		added, err := config.MergeEndpoints(file, []*config.Endpoint{{Path: "/users"}})
+		require.NoError(t, err, name)
+		assert.Equal(t, 1, added, name)
+
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, test.after, string(data), name)
+	}
+}
@@ -38,3 +38,18 @@ func parseLevel(level string) slog.Level {
 		return slog.LevelInfo
 	}
 }
+
+// AccessLog represents access log configuration.
+type AccessLog struct {
+	Format      string       `json:"format,omitempty"`      // "combined" (default), "json" or "logfmt"
+	Output      string       `json:"output,omitempty"`      // "stdout" (default), "stderr" or path to a file
+	MaxSize     int          `json:"maxSize,omitempty"`     // rotate the file once it exceeds given size in megabytes
+	MaxBackups  int          `json:"maxBackups,omitempty"`  // number of rotated files to keep
+	CaptureBody *CaptureBody `json:"captureBody,omitempty"` // optional capture of request and response bodies
+}
+
+// CaptureBody represents limits for capturing bodies into the access log.
+type CaptureBody struct {
+	Request  int `json:"request,omitempty"`  // max number of request body bytes to capture
+	Response int `json:"response,omitempty"` // max number of response body bytes to capture
+}
@@ -13,13 +13,39 @@ const defaultTimeout = 5 * time.Second
 
 // Mock represents configuration of API.
 type Mock struct {
-	Port         int         `json:"port,omitempty"`
-	Addr         string      `json:"addr,omitempty"`
-	ReadTimeout  string      `json:"readTimeout,omitempty"`
-	WriteTimeout string      `json:"writeTimeout,omitempty"`
-	IdleTimeout  string      `json:"idleTimeout,omitempty"`
-	LogLevel     string      `json:"logLevel,omitempty"`
-	Endpoints    []*Endpoint `json:"endpoints"`
+	Port         int           `json:"port,omitempty"`
+	Addr         string        `json:"addr,omitempty"`
+	ReadTimeout  string        `json:"readTimeout,omitempty"`
+	WriteTimeout string        `json:"writeTimeout,omitempty"`
+	IdleTimeout  string        `json:"idleTimeout,omitempty"`
+	LogLevel     string        `json:"logLevel,omitempty"`
+	AccessLog    *AccessLog    `json:"accessLog,omitempty"`
+	Seed         int64         `json:"seed,omitempty"`         // seed for reproducible random behaviour, random if not set
+	Latency      *Latency      `json:"latency,omitempty"`      // default latency for all endpoints
+	Throttle     *Throttle     `json:"throttle,omitempty"`     // default bandwidth limits for all endpoints
+	Compression  *Compression  `json:"compression,omitempty"`  // default compression for all endpoints
+	ProxyOptions *ProxyOptions `json:"proxyOptions,omitempty"` // default TLS, timeouts and connections of proxies
+	Fallback     *Fallback     `json:"fallback,omitempty"`     // responses to requests, which match none of the endpoints
+	Replay       *Replay       `json:"replay,omitempty"`       // recorded responses served before the endpoints
+	Auth         *Auth         `json:"auth,omitempty"`         // default credentials required by all endpoints
+	JWT          *JWT          `json:"jwt,omitempty"`          // token issuer signing JWTs for "auth.jwt"
+	OIDC         *OIDC         `json:"oidc,omitempty"`         // OAuth 2.0 and OpenID Connect provider
+	Endpoints    []*Endpoint   `json:"endpoints"`
+}
+
+// ApplyOverrides applies CLI flag overrides to the mock configuration.
+func (m *Mock) ApplyOverrides(overrides CLIOverrides) {
+	if overrides.Replay == "" {
+		return
+	}
+
+	replay := Replay{}
+	if m.Replay != nil {
+		replay = *m.Replay
+	}
+
+	replay.File = overrides.Replay
+	m.Replay = &replay
 }
 
 // NewMock loads API configuration from file.
@@ -85,40 +111,375 @@ func (m *Mock) ToConfig() Config {
 		cfg.Logger.Level = m.LogLevel
 	}
 
+	cfg.AccessLog = m.AccessLog
+
 	return cfg
 }
 
+// Fallback represents responses to requests, which match none of the endpoints.
+// Unmatched requests are proxied if "proxy" is set, get the nearest endpoints if "diagnostic" is set
+// or get the configured response otherwise.
+type Fallback struct {
+	Status       int               `json:"status,omitempty"`       // 404 (405 for unsupported methods) by default
+	JSON         any               `json:"json,omitempty"`         // response JSON
+	Body         string            `json:"body,omitempty"`         // response body, a template
+	Headers      map[string]string `json:"headers,omitempty"`      // response headers, values are templates
+	Proxy        string            `json:"proxy,omitempty"`        // upstream for unmatched requests, i.e. partial mocking
+	ProxyRules   *ProxyRules       `json:"proxyRules,omitempty"`   // transformations of proxied requests and responses
+	ProxyOptions *ProxyOptions     `json:"proxyOptions,omitempty"` // takes precedence over the global proxy options
+	ProxyChaos   *ProxyChaos       `json:"proxyChaos,omitempty"`   // faults injected into upstream responses
+	Diagnostic   bool              `json:"diagnostic,omitempty"`   // responds with JSON listing the nearest endpoints
+}
+
 // Endpoint represents API endpoint configuration.
 type Endpoint struct {
-	Methods   []string `json:"methods,omitempty"`
-	Status    int      `json:"status,omitempty"`
-	Path      string   `json:"path"`
-	Delay     int      `json:"delay,omitempty"`
-	JSONPath  string   `json:"jsonPath,omitempty"` // path to the JSON file with endpoint
-	JSON      any      `json:"json,omitempty"`
-	Proxy     string   `json:"proxy,omitempty"`
-	Static    string   `json:"static,omitempty"` // static file server
-	Errors    *Errors  `json:"errors,omitempty"`
-	AllowCors []string `json:"allowCors,omitempty"`
-	Dynamic   *struct {
-		Write *struct {
+	Methods      []string          `json:"methods,omitempty"`
+	Status       int               `json:"status,omitempty"`
+	Path         string            `json:"path"`
+	Delay        int               `json:"delay,omitempty"`
+	Latency      *Latency          `json:"latency,omitempty"`  // latency profile, takes precedence over "delay"
+	Throttle     *Throttle         `json:"throttle,omitempty"` // bandwidth limits, takes precedence over the global ones
+	JSONPath     string            `json:"jsonPath,omitempty"` // path to the JSON file with endpoint
+	JSON         any               `json:"json,omitempty"`
+	Body         string            `json:"body,omitempty"`       // raw response body
+	BodyBase64   string            `json:"bodyBase64,omitempty"` // base64 encoded binary response body
+	BodyFile     string            `json:"bodyFile,omitempty"`   // path to a file of any type with the response body
+	Generate     *Generate         `json:"generate,omitempty"`   // synthetic body of deterministic bytes
+	Proxy        string            `json:"proxy,omitempty"`
+	ProxyRules   *ProxyRules       `json:"proxyRules,omitempty"`   // transformations of proxied requests and responses
+	ProxyOptions *ProxyOptions     `json:"proxyOptions,omitempty"` // takes precedence over the global proxy options
+	ProxyChaos   *ProxyChaos       `json:"proxyChaos,omitempty"`   // faults injected into upstream responses
+	Upstreams    *Upstreams        `json:"upstreams,omitempty"`    // load-balanced proxy targets, instead of "proxy"
+	Static       string            `json:"static,omitempty"`       // static file server
+	Errors       *Errors           `json:"errors,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`     // response headers, values are templates
+	Cookies      []*Cookie         `json:"cookies,omitempty"`     // response cookies, values are templates
+	Trailers     map[string]string `json:"trailers,omitempty"`    // response trailers, values are templates
+	Match        *Match            `json:"match,omitempty"`       // conditions on the request
+	SOAPFault    *SOAPFault        `json:"soapFault,omitempty"`   // responds with a SOAP fault
+	Cache        *Cache            `json:"cache,omitempty"`       // validators and caching headers of the response
+	Variants     []*Variant        `json:"variants,omitempty"`    // bodies per media type negotiated by Accept
+	Compression  *Compression      `json:"compression,omitempty"` // takes precedence over the global compression
+	Auth         *Auth             `json:"auth,omitempty"`        // takes precedence over the global auth
+	AllowCors    []string          `json:"allowCors,omitempty"`
+	Dynamic      *struct {
+		PerPrincipal bool `json:"perPrincipal,omitempty"` // keeps entities of each authenticated principal apart
+		Write        *struct {
 			JSON *struct {
 				Name  string `json:"name"`  // entity name
 				Key   string `json:"key"`   // path/to/a/key to store from an incoming JSON
 				Value string `json:"value"` // path/to/a/value to store from an incoming JSON
 			} `json:"json,omitempty"`
+			Files *struct {
+				Name  string `json:"name"`            // entity name
+				Field string `json:"field,omitempty"` // multipart field with files, any field if empty
+				Key   string `json:"key,omitempty"`   // path/to/a/key in the form to store a file by, file name by default
+				Dir   string `json:"dir,omitempty"`   // directory to save files to, files are kept in the store if empty
+			} `json:"files,omitempty"`
 		} `json:"write,omitempty"`
 		Read *struct {
 			JSON *struct {
 				Name     string `json:"name"`               // entity name
 				KeyParam string `json:"keyParam,omitempty"` // key parameter name from the "path"
 			} `json:"json,omitempty"`
+			File *struct {
+				Name     string `json:"name"`     // entity name
+				KeyParam string `json:"keyParam"` // key parameter name from the "path"
+			} `json:"file,omitempty"`
 		} `json:"read,omitempty"`
 	} `json:"dynamic,omitempty"`
 }
 
+// Match represents conditions a request has to meet to be served by the endpoint,
+// endpoints sharing a path are tried in the order of definition.
+type Match struct {
+	Headers    map[string]string `json:"headers,omitempty"`    // exact values of request headers
+	SOAPAction string            `json:"soapAction,omitempty"` // SOAPAction header or "action" of SOAP 1.2 Content-Type
+	XPath      map[string]string `json:"xpath,omitempty"`      // XPath expressions with expected values in the XML body
+	Form       map[string]string `json:"form,omitempty"`       // exact values of urlencoded or multipart form fields
+	Claims     map[string]string `json:"claims,omitempty"`     // values of claims of the bearer JWT, not verified
+}
+
+// SOAPFault represents a SOAP fault response.
+type SOAPFault struct {
+	Version string `json:"version,omitempty"` // "1.1" (default) or "1.2"
+	Code    string `json:"code,omitempty"`    // defaults to "Server" in 1.1 and "Receiver" in 1.2
+	String  string `json:"string"`            // fault string, reason in 1.2
+	Actor   string `json:"actor,omitempty"`   // fault actor, role in 1.2
+	Detail  string `json:"detail,omitempty"`  // raw XML of the fault detail
+}
+
+// Cache represents validators and caching headers of the response,
+// conditional GET requests are answered with 304 Not Modified.
+type Cache struct {
+	ETag         string   `json:"etag,omitempty"`         // entity tag, computed from the body if empty
+	LastModified string   `json:"lastModified,omitempty"` // HTTP date, modification time of "jsonPath" or "bodyFile" if empty
+	Control      string   `json:"control,omitempty"`      // Cache-Control header
+	Vary         []string `json:"vary,omitempty"`         // Vary header
+}
+
+// Cookie represents a cookie set by the response.
+type Cookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"` // template
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"` // HTTP date, e.g. "Wed, 21 Oct 2026 07:28:00 GMT"
+	MaxAge   int    `json:"maxAge,omitempty"`  // in seconds, negative deletes the cookie
+	Secure   bool   `json:"secure,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	SameSite string `json:"sameSite,omitempty"` // "lax", "strict" or "none"
+}
+
 // Errors represents error simulation configuration.
 type Errors struct {
-	Sample   float32 `json:"sample,omitempty"`
-	Statuses []int   `json:"statuses,omitempty"`
+	Sample        float32           `json:"sample,omitempty"`
+	Mode          string            `json:"mode,omitempty"`   // "nth" (default) fails every Nth request, "random" by chance
+	Burst         int               `json:"burst,omitempty"`  // number of consecutive failures once triggered
+	Window        *ErrorWindow      `json:"window,omitempty"` // periodic outages
+	Statuses      []ErrorStatus     `json:"statuses,omitempty"`
+	Body          string            `json:"body,omitempty"`          // default body of error responses
+	Headers       map[string]string `json:"headers,omitempty"`       // default headers of error responses
+	Faults        []string          `json:"faults,omitempty"`        // "reset", "empty", "garbage", "truncate" or "stall"
+	TruncateAfter int               `json:"truncateAfter,omitempty"` // body bytes sent by "truncate", defaults to half
+}
+
+// ErrorWindow represents periodic outage, e.g. fail everything for 30s every 5 minutes.
+type ErrorWindow struct {
+	Every    string `json:"every"`            // period as Go duration string, e.g. "5m"
+	Duration string `json:"duration"`         // outage at the start of every period, e.g. "30s"
+	Offset   string `json:"offset,omitempty"` // shifts periods relative to the server start
+}
+
+// ErrorStatus represents an injected error status, in JSON it is either a plain status code or an object.
+type ErrorStatus struct {
+	Status  int               `json:"status"`
+	Weight  int               `json:"weight,omitempty"`  // relative chance of being picked, defaults to 1
+	Body    string            `json:"body,omitempty"`    // overrides the default body
+	Headers map[string]string `json:"headers,omitempty"` // added to the default headers, e.g. "Retry-After"
+}
+
+// UnmarshalJSON allows to define ErrorStatus as a plain status code.
+func (e *ErrorStatus) UnmarshalJSON(data []byte) error {
+	var status int
+
+	err := json.Unmarshal(data, &status)
+	if err == nil {
+		*e = ErrorStatus{Status: status}
+
+		return nil
+	}
+
+	type plain ErrorStatus
+
+	var obj plain
+
+	err = json.Unmarshal(data, &obj)
+	if err != nil {
+		return fmt.Errorf("unmarshalling error status: %w", err)
+	}
+
+	*e = ErrorStatus(obj)
+
+	return nil
+}
+
+// Latency represents simulated latency profile, all values are in milliseconds.
+type Latency struct {
+	Distribution string  `json:"distribution,omitempty"` // "fixed" (default), "uniform", "normal" or "lognormal"
+	Delay        int     `json:"delay,omitempty"`        // delay of the "fixed" distribution
+	Jitter       int     `json:"jitter,omitempty"`       // random deviation of the "fixed" distribution
+	Min          int     `json:"min,omitempty"`          // lower bound of "uniform", clamps other distributions
+	Max          int     `json:"max,omitempty"`          // upper bound of "uniform", clamps other distributions
+	Mean         float64 `json:"mean,omitempty"`         // mean of the "normal" distribution
+	StdDev       float64 `json:"stdDev,omitempty"`       // standard deviation of the "normal" distribution
+	P50          float64 `json:"p50,omitempty"`          // median of the "normal" and "lognormal" distributions
+	P99          float64 `json:"p99,omitempty"`          // 99th percentile of the "normal" and "lognormal" distributions
+}
+
+// Variant represents a response body of the given media type, one of them is chosen by Accept of the request.
+type Variant struct {
+	Type       string `json:"type"` // media type, e.g. "application/xml"
+	JSON       any    `json:"json,omitempty"`
+	JSONPath   string `json:"jsonPath,omitempty"`
+	Body       string `json:"body,omitempty"`
+	BodyBase64 string `json:"bodyBase64,omitempty"`
+	BodyFile   string `json:"bodyFile,omitempty"`
+}
+
+// Generate represents a synthetic response body of deterministic bytes, which needs no file on disk.
+type Generate struct {
+	Size    string `json:"size"`              // e.g. "2GiB", "500MB" or "1024" bytes
+	Seed    int64  `json:"seed,omitempty"`    // seed of the random bytes
+	Pattern string `json:"pattern,omitempty"` // "random" (default) or "sequence" of offsets modulo 256
+	Name    string `json:"name,omitempty"`    // file name for Content-Type and Content-Disposition
+}
+
+// Compression represents compression of response bodies negotiated by Accept-Encoding.
+type Compression struct {
+	Encodings []string `json:"encodings,omitempty"` // "br", "zstd" and "gzip" in order of preference, all of them if empty
+	MinSize   int      `json:"minSize,omitempty"`   // bodies with smaller Content-Length aren't compressed, ones without it are
+}
+
+// Auth represents credentials required by endpoints, any of the configured ones is accepted.
+type Auth struct {
+	Realm    string            `json:"realm,omitempty"`    // realm of WWW-Authenticate challenges, "gomock" by default
+	Basic    map[string]string `json:"basic,omitempty"`    // passwords of Basic auth by usernames
+	APIKey   *APIKey           `json:"apiKey,omitempty"`   // API keys in a header or a query parameter
+	Bearer   map[string]string `json:"bearer,omitempty"`   // principals by static bearer tokens, the token if empty
+	JWT      *JWTAuth          `json:"jwt,omitempty"`      // bearer JWTs signed by the "jwt" issuer
+	Allow    []string          `json:"allow,omitempty"`    // principals allowed, others get 403, anyone if empty
+	Disabled bool              `json:"disabled,omitempty"` // turns the global auth off for the endpoint
+}
+
+// APIKey represents API keys of Auth.
+type APIKey struct {
+	Header string            `json:"header,omitempty"` // e.g. "X-API-Key"
+	Query  string            `json:"query,omitempty"`  // e.g. "api_key"
+	Keys   map[string]string `json:"keys"`             // principals by keys, the key if empty
+}
+
+// JWTAuth represents validation of bearer JWTs, signature and expiry are always checked.
+type JWTAuth struct {
+	Issuer    string   `json:"issuer,omitempty"`    // expected "iss" claim, any if empty
+	Audience  string   `json:"audience,omitempty"`  // expected in "aud" claim, any if empty
+	Scopes    []string `json:"scopes,omitempty"`    // required in "scope" or "scp" claims, 403 otherwise
+	Principal string   `json:"principal,omitempty"` // claim of the principal, "sub" by default
+}
+
+// JWT represents the token issuer, which signs JWTs with RS256 and publishes its public key as JWKS.
+type JWT struct {
+	Issuer    string         `json:"issuer,omitempty"`    // "iss" claim, URL of the server by default
+	TokenPath string         `json:"tokenPath,omitempty"` // path of the token endpoint, "/token" by default
+	JWKSPath  string         `json:"jwksPath,omitempty"`  // path of the JWKS, "/.well-known/jwks.json" by default
+	KeyFile   string         `json:"keyFile,omitempty"`   // PEM RSA private key, generated at start if empty
+	TTL       string         `json:"ttl,omitempty"`       // lifetime of tokens as a Go duration, "1h" by default
+	Claims    map[string]any `json:"claims,omitempty"`    // default claims of issued tokens
+}
+
+// OIDC represents OAuth 2.0 and OpenID Connect provider, its tokens are signed by the "jwt" issuer.
+type OIDC struct {
+	AutoApprove   bool          `json:"autoApprove,omitempty"`   // signs in "login_hint" or the first user without a form
+	Users         []*OIDCUser   `json:"users,omitempty"`         // users of the authorization code flow
+	Clients       []*OIDCClient `json:"clients"`                 // registered clients
+	AuthorizePath string        `json:"authorizePath,omitempty"` // "/authorize" by default
+	UserinfoPath  string        `json:"userinfoPath,omitempty"`  // "/userinfo" by default
+	AllowCors     []string      `json:"allowCors,omitempty"`     // origins allowed to call the provider, any by default
+}
+
+// OIDCUser represents a user of OIDC.
+type OIDCUser struct {
+	Username string         `json:"username"`           // "sub" claim
+	Password string         `json:"password,omitempty"` // password of the login form
+	Claims   map[string]any `json:"claims,omitempty"`   // e.g. "email" or "name", in tokens and userinfo
+}
+
+// OIDCClient represents a client of OIDC.
+type OIDCClient struct {
+	ID           string         `json:"id"`
+	Secret       string         `json:"secret,omitempty"`       // public clients have none and have to use PKCE
+	RedirectURIs []string       `json:"redirectUris,omitempty"` // allowed redirect URIs, any if empty
+	Scopes       []string       `json:"scopes,omitempty"`       // scopes allowed to request, any if empty
+	Claims       map[string]any `json:"claims,omitempty"`       // claims of client credentials tokens
+}
+
+// Throttle represents bandwidth limits and slow-drip behaviour of response bodies.
+type Throttle struct {
+	BytesPerSecond int `json:"bytesPerSecond,omitempty"` // max throughput of a response body
+	ChunkSize      int `json:"chunkSize,omitempty"`      // size of fragments the body is sent in
+	ChunkDelay     int `json:"chunkDelay,omitempty"`     // delay in milliseconds between fragments
+}
+
+// ProxyRules represents transformations of proxied requests and upstream responses.
+type ProxyRules struct {
+	Request  *ProxyRequestRules  `json:"request,omitempty"`
+	Response *ProxyResponseRules `json:"response,omitempty"`
+}
+
+// ProxyRequestRules represents transformations of requests before they are forwarded upstream.
+type ProxyRequestRules struct {
+	SetHeaders    map[string]string `json:"setHeaders,omitempty"`    // headers to add or replace, values are templates
+	RemoveHeaders []string          `json:"removeHeaders,omitempty"` // headers to remove
+	StripPrefix   string            `json:"stripPrefix,omitempty"`   // prefix removed from the path
+	Rewrite       []*PathRewrite    `json:"rewrite,omitempty"`       // path rewrites applied in order
+}
+
+// PathRewrite represents a regular expression rewrite of the request path.
+type PathRewrite struct {
+	Pattern     string `json:"pattern"`     // regular expression matched against the path
+	Replacement string `json:"replacement"` // replacement with $1 or ${name} references to the groups
+}
+
+// ProxyResponseRules represents transformations of upstream responses.
+type ProxyResponseRules struct {
+	Status        int               `json:"status,omitempty"`        // overrides status of the response
+	SetHeaders    map[string]string `json:"setHeaders,omitempty"`    // headers to add or replace, values are templates
+	RemoveHeaders []string          `json:"removeHeaders,omitempty"` // headers to remove
+	JSONPatch     []*JSONPatchOp    `json:"jsonPatch,omitempty"`     // RFC 6902 JSON Patch of a JSON body
+	MergePatch    any               `json:"mergePatch,omitempty"`    // RFC 7386 JSON Merge Patch of a JSON body
+}
+
+// JSONPatchOp represents an operation of RFC 6902 JSON Patch.
+type JSONPatchOp struct {
+	Op    string          `json:"op"`             // "add", "remove", "replace", "move", "copy" or "test"
+	Path  string          `json:"path"`           // JSON Pointer to the target location, e.g. "/items/0/price"
+	From  string          `json:"from,omitempty"` // JSON Pointer to the source location of "move" and "copy"
+	Value json.RawMessage `json:"value"`          // value of "add", "replace" and "test", null is a value too
+}
+
+// ProxyOptions represents TLS, timeouts and connection settings of requests to proxy upstreams.
+type ProxyOptions struct {
+	CACert                string `json:"caCert,omitempty"`                // PEM file of CAs trusted in addition to the system ones
+	ClientCert            string `json:"clientCert,omitempty"`            // PEM file of the client certificate
+	ClientKey             string `json:"clientKey,omitempty"`             // PEM file of the client certificate key
+	InsecureSkipVerify    bool   `json:"insecureSkipVerify,omitempty"`    // doesn't verify certificates of upstreams
+	ServerName            string `json:"serverName,omitempty"`            // overrides server name for SNI and verification
+	DialTimeout           string `json:"dialTimeout,omitempty"`           // e.g. "5s", 30s by default
+	TLSHandshakeTimeout   string `json:"tlsHandshakeTimeout,omitempty"`   // 10s by default
+	ResponseHeaderTimeout string `json:"responseHeaderTimeout,omitempty"` // no timeout by default
+	IdleConnTimeout       string `json:"idleConnTimeout,omitempty"`       // 90s by default
+	MaxIdleConnsPerHost   int    `json:"maxIdleConnsPerHost,omitempty"`   // 2 by default
+	HTTP2                 string `json:"http2,omitempty"`                 // "off" or "h2c", negotiated over TLS by default
+	OutboundProxy         string `json:"outboundProxy,omitempty"`         // HTTP(S) or SOCKS5 proxy URL or "direct"
+}
+
+// Upstreams represents load-balanced proxy targets with passive health checks and failover.
+type Upstreams struct {
+	Targets          []*UpstreamTarget `json:"targets"`
+	Strategy         string            `json:"strategy,omitempty"`         // "roundRobin" (default), "random" or "weighted"
+	FailoverStatuses []int             `json:"failoverStatuses,omitempty"` // statuses to fail over on, any 5xx by default
+	MaxFails         int               `json:"maxFails,omitempty"`         // consecutive failures marking a target down, 1 by default
+	FailTimeout      string            `json:"failTimeout,omitempty"`      // how long a down target is skipped, "10s" by default
+	Fallback         bool              `json:"fallback,omitempty"`         // serves the mock response if every target fails
+}
+
+// UpstreamTarget represents a proxy target of Upstreams.
+type UpstreamTarget struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight,omitempty"` // weight of the "weighted" strategy, 1 by default
+}
+
+// ProxyChaos represents faults injected into upstream responses of proxies.
+type ProxyChaos struct {
+	Errors        *Errors  `json:"errors,omitempty"`        // replaces upstream responses, no network faults
+	Latency       *Latency `json:"latency,omitempty"`       // delay after the upstream responds
+	Corrupt       float32  `json:"corrupt,omitempty"`       // fraction of responses with randomly flipped body bytes
+	Truncate      float32  `json:"truncate,omitempty"`      // fraction of responses with bodies cut off
+	TruncateAfter int      `json:"truncateAfter,omitempty"` // body bytes kept by "truncate", defaults to half
+	DropHeaders   []string `json:"dropHeaders,omitempty"`   // headers removed from upstream responses
+}
+
+// Replay represents answering requests with responses recorded in a capture file.
+type Replay struct {
+	File   string       `json:"file"`             // HAR or gomock capture file, e.g. recorded with -record
+	Match  *ReplayMatch `json:"match,omitempty"`  // keys besides method and path, query and body if not set
+	Ignore []string     `json:"ignore,omitempty"` // volatile query parameters and JSON body fields, e.g. "nonce" or "meta.ts"
+	Strict bool         `json:"strict,omitempty"` // fails requests without a recorded response matching every key
+}
+
+// ReplayMatch represents keys of requests compared to recorded requests.
+type ReplayMatch struct {
+	Query   []string `json:"query,omitempty"`   // query parameters to compare, all of them if empty
+	Headers []string `json:"headers,omitempty"` // headers to compare
+	Body    bool     `json:"body,omitempty"`    // compares normalised bodies
 }
@@ -1,6 +1,7 @@
 package config_test
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -156,6 +157,44 @@ func TestApplyOverrides_EmptyKeepsDefaults(t *testing.T) {
 	assert.Equal(t, "info", cfg.Logger.Level)
 }
 
+func TestApplyOverrides_AccessLog(t *testing.T) {
+	t.Parallel()
+
+	mock := config.Mock{AccessLog: &config.AccessLog{Format: "json", Output: "stdout"}}
+	cfg := mock.ToConfig()
+	cfg.ApplyOverrides(config.CLIOverrides{AccessLog: "access.log"})
+
+	require.NotNil(t, cfg.AccessLog)
+	assert.Equal(t, "json", cfg.AccessLog.Format)
+	assert.Equal(t, "access.log", cfg.AccessLog.Output)
+	assert.Equal(t, "stdout", mock.AccessLog.Output)
+}
+
+func TestApplyOverrides_AccessLogDisabledByDefault(t *testing.T) {
+	t.Parallel()
+
+	mock := config.Mock{}
+	cfg := mock.ToConfig()
+	cfg.ApplyOverrides(config.CLIOverrides{})
+
+	assert.Nil(t, cfg.AccessLog)
+}
+
+func TestMockApplyOverrides_Replay(t *testing.T) {
+	t.Parallel()
+
+	mock := config.Mock{Replay: &config.Replay{File: "staging.har", Strict: true}}
+	mock.ApplyOverrides(config.CLIOverrides{Replay: "/tmp/capture.json"})
+
+	require.NotNil(t, mock.Replay)
+	assert.Equal(t, "/tmp/capture.json", mock.Replay.File)
+	assert.True(t, mock.Replay.Strict)
+
+	mock = config.Mock{}
+	mock.ApplyOverrides(config.CLIOverrides{})
+	assert.Nil(t, mock.Replay)
+}
+
 func TestNewMock_WithServerFields(t *testing.T) {
 	t.Parallel()
 
@@ -190,3 +229,19 @@ func TestNewMock_WithServerFields(t *testing.T) {
 	assert.Len(t, mck.Endpoints, 1)
 	assert.Equal(t, "/test", mck.Endpoints[0].Path)
 }
+
+func TestErrorStatus_UnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	var errs config.Errors
+
+	err := json.Unmarshal([]byte(`{
+		"statuses": [500, {"status": 503, "weight": 3, "headers": {"Retry-After": "10"}}]
+	}`), &errs)
+	require.NoError(t, err)
+
+	assert.Equal(t, []config.ErrorStatus{
+		{Status: 500},
+		{Status: 503, Weight: 3, Headers: map[string]string{"Retry-After": "10"}},
+	}, errs.Statuses)
+}
@@ -3,8 +3,10 @@ module github.com/smeshkov/gomock
 go 1.26
 
 require (
+	github.com/andybalholm/brotli v1.2.0
 	github.com/go-chi/chi/v5 v5.2.4
 	github.com/gorilla/handlers v1.5.1
+	github.com/klauspost/compress v1.20.1
 	github.com/stretchr/testify v1.4.0
 	gopkg.in/fsnotify.v1 v1.4.7
 )
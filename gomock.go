@@ -0,0 +1,302 @@
+// Package gomock embeds gomock mock server in Go tests.
+//
+//	server := gomock.New().
+//		GET("/users").JSON([]map[string]any{{"id": 1}}).
+//		POST("/users").Status(http.StatusCreated).
+//		Start(t)
+//
+//	resp, err := http.Get(server.URL + "/users")
+package gomock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smeshkov/gomock/app"
+	"github.com/smeshkov/gomock/config"
+)
+
+const (
+	version         = "embedded"
+	shutdownTimeout = 5 * time.Second
+)
+
+// Mock is a builder of a mock server, methods like GET and POST add endpoints,
+// other methods configure the last added endpoint.
+type Mock struct {
+	mock    config.Mock
+	dir     string
+	current *config.Endpoint
+	strict  bool
+}
+
+// New creates an empty mock server builder.
+func New() *Mock {
+	return &Mock{dir: "."}
+}
+
+// FromConfig creates a builder of the mock server loaded from the mock configuration file.
+func FromConfig(file string) (*Mock, error) {
+	mck, dir, err := config.NewMock(file)
+	if err != nil {
+		return nil, fmt.Errorf("loading mock configuration: %w", err)
+	}
+
+	return &Mock{mock: mck, dir: dir}, nil
+}
+
+// Dir sets the directory relative paths of the endpoints (e.g. "jsonPath" or "bodyFile") are resolved against.
+func (m *Mock) Dir(dir string) *Mock {
+	m.dir = dir
+
+	return m
+}
+
+// Strict makes the test started with Start fail on requests, which matched none of the endpoints.
+func (m *Mock) Strict() *Mock {
+	m.strict = true
+
+	return m
+}
+
+// Configure changes the mock configuration directly, e.g. to set "seed" or global "latency".
+func (m *Mock) Configure(configure func(mck *config.Mock)) *Mock {
+	configure(&m.mock)
+
+	return m
+}
+
+// Endpoint adds the endpoint as it is.
+func (m *Mock) Endpoint(endpoint *config.Endpoint) *Mock {
+	m.mock.Endpoints = append(m.mock.Endpoints, endpoint)
+	m.current = endpoint
+
+	return m
+}
+
+// Handle adds an endpoint of the method and the path, which uses chi patterns, e.g. "/users/{id}".
+func (m *Mock) Handle(method, path string) *Mock {
+	return m.Endpoint(&config.Endpoint{Methods: []string{method}, Path: path})
+}
+
+// GET adds an endpoint for GET requests.
+func (m *Mock) GET(path string) *Mock { return m.Handle(http.MethodGet, path) }
+
+// POST adds an endpoint for POST requests.
+func (m *Mock) POST(path string) *Mock { return m.Handle(http.MethodPost, path) }
+
+// PUT adds an endpoint for PUT requests.
+func (m *Mock) PUT(path string) *Mock { return m.Handle(http.MethodPut, path) }
+
+// PATCH adds an endpoint for PATCH requests.
+func (m *Mock) PATCH(path string) *Mock { return m.Handle(http.MethodPatch, path) }
+
+// DELETE adds an endpoint for DELETE requests.
+func (m *Mock) DELETE(path string) *Mock { return m.Handle(http.MethodDelete, path) }
+
+// Status sets status code of the response.
+func (m *Mock) Status(status int) *Mock {
+	m.endpoint().Status = status
+
+	return m
+}
+
+// JSON sets the value encoded as JSON as the response body.
+func (m *Mock) JSON(value any) *Mock {
+	m.endpoint().JSON = value
+
+	return m
+}
+
+// Body sets the response body, it is a template, see "Response headers" in README.
+func (m *Mock) Body(body string) *Mock {
+	m.endpoint().Body = body
+
+	return m
+}
+
+// BodyFile sets the file with the response body.
+func (m *Mock) BodyFile(path string) *Mock {
+	m.endpoint().BodyFile = path
+
+	return m
+}
+
+// Header sets a response header, the value is a template.
+func (m *Mock) Header(name, value string) *Mock {
+	endpoint := m.endpoint()
+	if endpoint.Headers == nil {
+		endpoint.Headers = map[string]string{}
+	}
+
+	endpoint.Headers[name] = value
+
+	return m
+}
+
+// Delay delays the response.
+func (m *Mock) Delay(delay time.Duration) *Mock {
+	m.endpoint().Delay = int(delay / time.Millisecond)
+
+	return m
+}
+
+// Proxy proxies requests of the endpoint to the URL.
+func (m *Mock) Proxy(target string) *Mock {
+	m.endpoint().Proxy = target
+
+	return m
+}
+
+// MatchHeader makes the endpoint serve only requests with the header value.
+func (m *Mock) MatchHeader(name, value string) *Mock {
+	endpoint := m.endpoint()
+	if endpoint.Match == nil {
+		endpoint.Match = &config.Match{}
+	}
+
+	if endpoint.Match.Headers == nil {
+		endpoint.Match.Headers = map[string]string{}
+	}
+
+	endpoint.Match.Headers[name] = value
+
+	return m
+}
+
+func (m *Mock) endpoint() *config.Endpoint {
+	if m.current == nil {
+		panic("gomock: add an endpoint with GET, POST, Handle, etc. before configuring its response")
+	}
+
+	return m.current
+}
+
+// Start starts the mock server on a random local port, the server is closed when the test finishes.
+func (m *Mock) Start(t testing.TB) *Server {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(nil)
+
+	server := m.newServer(srv.Listener.Addr().String())
+	srv.Config.Handler = server.handler
+	srv.Start()
+
+	server.URL = srv.URL
+	server.close = func() error {
+		srv.Close()
+
+		return nil
+	}
+
+	t.Cleanup(server.Close)
+
+	if m.strict {
+		t.Cleanup(func() {
+			server.VerifyNoUnmatched(t)
+		})
+	}
+
+	return server
+}
+
+// Serve starts the mock server on the address, e.g. "127.0.0.1:0" for a random local port.
+func (m *Mock) Serve(addr string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", addr, err)
+	}
+
+	server := m.newServer(listener.Addr().String())
+
+	httpServer := &http.Server{
+		Handler:           server.handler,
+		ReadHeaderTimeout: shutdownTimeout,
+	}
+
+	served := make(chan error, 1)
+
+	go func() {
+		err := httpServer.Serve(listener)
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+
+		served <- err
+	}()
+
+	server.URL = "http://" + listener.Addr().String()
+	server.close = func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err := httpServer.Shutdown(ctx)
+		if err != nil {
+			return fmt.Errorf("shutting down mock server: %w", err)
+		}
+
+		return <-served
+	}
+
+	return server, nil
+}
+
+func (m *Mock) newServer(addr string) *Server {
+	cfg := m.mock.ToConfig()
+	cfg.Server.Addr = addr
+
+	journal := app.NewJournal(0)
+
+	return &Server{
+		journal: journal,
+		handler: journal.Middleware(app.RegisterHandlers(version, m.dir, &cfg, &m.mock)),
+	}
+}
+
+// Server is a running mock server.
+type Server struct {
+	URL string // base URL of the server, e.g. "http://127.0.0.1:49152"
+
+	journal *app.Journal
+	handler http.Handler
+	close   func() error
+	once    sync.Once
+	err     error
+}
+
+// Requests returns requests received by the server with their responses in the order they were received.
+func (s *Server) Requests() []*app.JournalEntry {
+	return s.journal.Entries()
+}
+
+// Save writes received requests with their responses to the file, in HAR 1.2 format if the file has ".har"
+// extension, e.g. to inspect them in browser devtools, and in gomock capture format otherwise.
+func (s *Server) Save(file string) error {
+	return s.journal.Save(file) //nolint:wrapcheck // errors of the journal describe the file
+}
+
+// Reset forgets received requests.
+func (s *Server) Reset() {
+	s.journal.Reset()
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	_ = s.Shutdown()
+}
+
+// Shutdown stops the server and returns an error of serving or stopping it, it can be called many times.
+func (s *Server) Shutdown() error {
+	s.once.Do(func() {
+		s.err = s.close()
+	})
+
+	return s.err
+}
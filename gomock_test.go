@@ -0,0 +1,90 @@
+package gomock_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smeshkov/gomock"
+)
+
+func call(t *testing.T, method, url, body string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), method, url, strings.NewReader(body))
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp.StatusCode, string(data)
+}
+
+func TestStart(t *testing.T) {
+	t.Parallel()
+
+	server := gomock.New().
+		GET("/users/{id}").JSON(map[string]any{"id": 1}).Header("X-User", "{{.Params.id}}").
+		POST("/users").Status(http.StatusCreated).Body("created").
+		Start(t)
+
+	status, body := call(t, http.MethodGet, server.URL+"/users/1", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"id": 1}`, body)
+
+	status, body = call(t, http.MethodPost, server.URL+"/users", `{"name": "foo"}`)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "created", body)
+
+	status, _ = call(t, http.MethodGet, server.URL+"/unknown", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	requests := server.Requests()
+	require.Len(t, requests, 3)
+
+	assert.Equal(t, "/users/{id}", requests[0].Endpoint)
+	assert.Equal(t, "1", requests[0].Response.Header.Get("X-User"))
+	assert.True(t, requests[0].Matched())
+
+	assert.Equal(t, http.MethodPost, requests[1].Request.Method)
+	assert.JSONEq(t, `{"name": "foo"}`, requests[1].Request.Body)
+	assert.Equal(t, "created", requests[1].Response.Body)
+
+	assert.Equal(t, "/unknown", requests[2].Request.Path)
+	assert.False(t, requests[2].Matched())
+
+	server.Reset()
+	assert.Empty(t, server.Requests())
+}
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+
+	server, err := gomock.New().GET("/ping").Body("pong").Serve("127.0.0.1:0")
+	require.NoError(t, err)
+
+	status, body := call(t, http.MethodGet, server.URL+"/ping", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "pong", body)
+
+	require.NoError(t, server.Shutdown())
+	require.NoError(t, server.Shutdown())
+
+	_, err = http.Get(server.URL + "/ping") //nolint:noctx // the server is down
+	assert.Error(t, err)
+}
+
+func TestEndpointBeforeMethodPanics(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() { gomock.New().Status(http.StatusOK) })
+}
@@ -0,0 +1,281 @@
+package gomock
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/smeshkov/gomock/app"
+)
+
+// anyTimes is a count of expected requests meaning "at least once".
+const anyTimes = -1
+
+// Expectation describes requests expected by the server.
+type Expectation struct {
+	method       string
+	path         string
+	query        map[string]string
+	headers      map[string]string
+	body         *string
+	bodyContains string
+	json         any
+	jsonRaw      string
+	times        int
+}
+
+// Expect creates an expectation of requests with the method and the path, the path can have
+// chi-like parameters, e.g. "/users/{id}", and end with "*" to match any rest of the path.
+// By default the request is expected at least once.
+func Expect(method, path string) *Expectation {
+	return &Expectation{method: method, path: path, times: anyTimes}
+}
+
+// Query expects the query parameter.
+func (e *Expectation) Query(name, value string) *Expectation {
+	if e.query == nil {
+		e.query = map[string]string{}
+	}
+
+	e.query[name] = value
+
+	return e
+}
+
+// Header expects the request header.
+func (e *Expectation) Header(name, value string) *Expectation {
+	if e.headers == nil {
+		e.headers = map[string]string{}
+	}
+
+	e.headers[name] = value
+
+	return e
+}
+
+// Body expects exactly the body.
+func (e *Expectation) Body(body string) *Expectation {
+	e.body = &body
+
+	return e
+}
+
+// BodyContains expects the body to contain the substring.
+func (e *Expectation) BodyContains(substr string) *Expectation {
+	e.bodyContains = substr
+
+	return e
+}
+
+// JSON expects the body to be the same JSON regardless of formatting and order of attributes.
+func (e *Expectation) JSON(raw string) *Expectation {
+	e.jsonRaw = raw
+
+	err := json.Unmarshal([]byte(raw), &e.json)
+	if err != nil {
+		panic(fmt.Sprintf("gomock: invalid expected JSON %s: %v", raw, err))
+	}
+
+	return e
+}
+
+// Times expects exactly n requests.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+
+	return e
+}
+
+// Never expects no requests.
+func (e *Expectation) Never() *Expectation {
+	return e.Times(0)
+}
+
+func (e *Expectation) String() string {
+	return e.method + " " + e.path
+}
+
+// difference is a field of a request, which doesn't meet the expectation.
+type difference struct {
+	field    string
+	expected string
+	actual   string
+	weight   int // method and path differences weigh more to find the closest request
+}
+
+const (
+	weightField    = 1
+	weightLocation = 10
+)
+
+// diff returns differences of the request from the expectation, none if the request meets it.
+func (e *Expectation) diff(req *app.RecordedRequest) []difference {
+	var diffs []difference
+
+	if !strings.EqualFold(e.method, req.Method) {
+		diffs = append(diffs, difference{"method", e.method, req.Method, weightLocation})
+	}
+
+	if !app.RouteMatches(e.path, req.Path) {
+		diffs = append(diffs, difference{"path", e.path, req.Path, weightLocation})
+	}
+
+	for name, value := range e.query {
+		if actual := req.Query.Get(name); actual != value {
+			diffs = append(diffs, difference{"query " + name, value, actual, weightField})
+		}
+	}
+
+	for name, value := range e.headers {
+		if actual := req.Header.Get(name); actual != value {
+			diffs = append(diffs, difference{"header " + name, value, actual, weightField})
+		}
+	}
+
+	if e.body != nil && *e.body != req.Body {
+		diffs = append(diffs, difference{"body", *e.body, req.Body, weightField})
+	}
+
+	if e.bodyContains != "" && !strings.Contains(req.Body, e.bodyContains) {
+		diffs = append(diffs, difference{"body", "containing " + e.bodyContains, req.Body, weightField})
+	}
+
+	if e.jsonRaw != "" {
+		var actual any
+		if json.Unmarshal([]byte(req.Body), &actual) != nil || !reflect.DeepEqual(e.json, actual) {
+			diffs = append(diffs, difference{"JSON body", e.jsonRaw, req.Body, weightField})
+		}
+	}
+
+	return diffs
+}
+
+// matching returns indexes of the entries, which meet the expectation.
+func (e *Expectation) matching(entries []*app.JournalEntry) []int {
+	var indexes []int
+
+	for idx, entry := range entries {
+		if len(e.diff(&entry.Request)) == 0 {
+			indexes = append(indexes, idx)
+		}
+	}
+
+	return indexes
+}
+
+// closest returns the request with the least weighty differences from the expectation.
+func (e *Expectation) closest(entries []*app.JournalEntry) (*app.JournalEntry, []difference) {
+	var (
+		best      *app.JournalEntry
+		bestDiffs []difference
+		bestScore int
+	)
+
+	for _, entry := range entries {
+		diffs := e.diff(&entry.Request)
+
+		score := 0
+		for _, diff := range diffs {
+			score += diff.weight
+		}
+
+		if best == nil || score < bestScore {
+			best, bestDiffs, bestScore = entry, diffs, score
+		}
+	}
+
+	return best, bestDiffs
+}
+
+// Verify checks that the server received requests meeting the expectation, reports differences
+// of the closest request otherwise. Returns true if the expectation is met.
+func (s *Server) Verify(t testing.TB, expectation *Expectation) bool {
+	t.Helper()
+
+	entries := s.Requests()
+	count := len(expectation.matching(entries))
+
+	switch {
+	case expectation.times == anyTimes && count > 0:
+		return true
+	case expectation.times == count:
+		return true
+	case expectation.times == anyTimes:
+		t.Errorf("expected %s at least once, got none\n%s", expectation, describeClosest(expectation, entries))
+	default:
+		t.Errorf("expected %s %d time(s), got %d\n%s", expectation, expectation.times, count,
+			describeClosest(expectation, entries))
+	}
+
+	return false
+}
+
+// VerifyInOrder checks that the server received requests meeting the expectations in the given order,
+// other requests in between are allowed. Returns true if the order is met.
+func (s *Server) VerifyInOrder(t testing.TB, expectations ...*Expectation) bool {
+	t.Helper()
+
+	entries := s.Requests()
+	next := 0
+
+	for idx, expectation := range expectations {
+		indexes := expectation.matching(entries[next:])
+		if len(indexes) == 0 {
+			t.Errorf("expected %s (#%d in order) after %d request(s), got none\n%s", expectation, idx+1, next,
+				describeClosest(expectation, entries[next:]))
+
+			return false
+		}
+
+		next += indexes[0] + 1
+	}
+
+	return true
+}
+
+// VerifyNoUnmatched checks that every request was served by one of the endpoints.
+// Returns true if there were no unmatched requests.
+func (s *Server) VerifyNoUnmatched(t testing.TB) bool {
+	t.Helper()
+
+	var unmatched []string
+
+	for _, entry := range s.Requests() {
+		if !entry.Matched() {
+			unmatched = append(unmatched, fmt.Sprintf("  %s %s -> %d", entry.Request.Method, entry.Request.URL,
+				entry.Response.Status))
+		}
+	}
+
+	if len(unmatched) == 0 {
+		return true
+	}
+
+	t.Errorf("unexpected request(s), which matched no endpoint:\n%s", strings.Join(unmatched, "\n"))
+
+	return false
+}
+
+// describeClosest describes differences of the closest request from the expectation.
+func describeClosest(expectation *Expectation, entries []*app.JournalEntry) string {
+	closest, diffs := expectation.closest(entries)
+	if closest == nil {
+		return "no requests received"
+	}
+
+	var buf strings.Builder
+
+	fmt.Fprintf(&buf, "closest request: %s %s\n", closest.Request.Method, closest.Request.URL)
+
+	for _, diff := range diffs {
+		fmt.Fprintf(&buf, "  %s:\n    - %q\n    + %q\n", diff.field, diff.expected, diff.actual)
+	}
+
+	if len(diffs) == 0 {
+		buf.WriteString("  it meets the expectation, but the number of requests differs\n")
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,84 @@
+package gomock_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/smeshkov/gomock"
+)
+
+// fakeT records failures instead of failing the test.
+type fakeT struct {
+	testing.TB
+
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	server := gomock.New().
+		POST("/users").Status(http.StatusCreated).
+		GET("/users/{id}").JSON(map[string]any{"id": 1}).
+		Start(t)
+
+	call(t, http.MethodPost, server.URL+"/users", `{"name": "foo", "age": 3}`)
+	call(t, http.MethodGet, server.URL+"/users/1?expand=true", "")
+	call(t, http.MethodGet, server.URL+"/users/2", "")
+
+	assert.True(t, server.Verify(t, gomock.Expect(http.MethodPost, "/users").JSON(`{"age": 3, "name": "foo"}`).Times(1)))
+	assert.True(t, server.Verify(t, gomock.Expect(http.MethodGet, "/users/{id}").Times(2)))
+	assert.True(t, server.Verify(t, gomock.Expect(http.MethodGet, "/users/*").Query("expand", "true")))
+	assert.True(t, server.Verify(t, gomock.Expect(http.MethodDelete, "/users/{id}").Never()))
+	assert.True(t, server.VerifyInOrder(t,
+		gomock.Expect(http.MethodPost, "/users"),
+		gomock.Expect(http.MethodGet, "/users/2"),
+	))
+
+	fake := &fakeT{TB: t}
+
+	assert.False(t, server.Verify(fake, gomock.Expect(http.MethodPost, "/users").BodyContains(`"bar"`)))
+	assert.Len(t, fake.errors, 1)
+	assert.Contains(t, fake.errors[0], "expected POST /users at least once, got none")
+	assert.Contains(t, fake.errors[0], "closest request: POST /users\n  body:\n    - \"containing \\\"bar\\\"\"")
+
+	fake = &fakeT{TB: t}
+
+	assert.False(t, server.Verify(fake, gomock.Expect(http.MethodGet, "/users/{id}").Times(1)))
+	assert.Contains(t, fake.errors[0], "expected GET /users/{id} 1 time(s), got 2")
+
+	fake = &fakeT{TB: t}
+
+	assert.False(t, server.VerifyInOrder(fake,
+		gomock.Expect(http.MethodGet, "/users/2"),
+		gomock.Expect(http.MethodPost, "/users"),
+	))
+	assert.Contains(t, fake.errors[0], "expected POST /users (#2 in order) after 3 request(s), got none")
+}
+
+func TestVerifyNoUnmatched(t *testing.T) {
+	t.Parallel()
+
+	server := gomock.New().GET("/users").Start(t)
+
+	call(t, http.MethodGet, server.URL+"/users", "")
+	call(t, http.MethodGet, server.URL+"/healthcheck", "")
+	call(t, http.MethodGet, server.URL+"/version", "")
+	assert.True(t, server.VerifyNoUnmatched(t))
+
+	call(t, http.MethodGet, server.URL+"/orders?page=2", "")
+
+	fake := &fakeT{TB: t}
+
+	assert.False(t, server.VerifyNoUnmatched(fake))
+	assert.Equal(t, []string{"unexpected request(s), which matched no endpoint:\n  GET /orders?page=2 -> 404"}, fake.errors)
+}